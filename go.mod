@@ -14,9 +14,9 @@ require (
 	github.com/goodsign/monday v1.0.0
 	github.com/gotk3/gotk3 v0.5.3-0.20210326060404-6328e5470ece
 	github.com/gregjones/httpcache v0.0.0-20190611155906-901d90724c79
-	github.com/peterbourgon/diskv v2.0.1+incompatible
 	github.com/pkg/errors v0.9.1
 	github.com/skratchdot/open-golang v0.0.0-20200116055534-eef842397966
 	github.com/twmb/murmur3 v1.1.3
 	github.com/zalando/go-keyring v0.0.0-20200121091418-667557018717
+	golang.org/x/crypto v0.0.0-20201016220609-9e8e0b390897
 )
@@ -0,0 +1,217 @@
+// Package crash writes crash reports when cchat-gtk panics, so that they are
+// not lost with the process.
+package crash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/diamondburned/cchat-gtk/internal/profile"
+	"github.com/pkg/errors"
+)
+
+// RecentEntries is the number of the latest log entries included in a report.
+const RecentEntries = 100
+
+// MaxSeen is the number of reports kept after they're marked as seen. Older
+// ones are removed.
+const MaxSeen = 10
+
+const (
+	reportExt = ".txt"
+	seenExt   = ".seen"
+)
+
+// ReportDir returns the directory containing crash reports.
+func ReportDir() string {
+	return filepath.Join(profile.CacheDir(), "crashes")
+}
+
+var state struct {
+	sync.Mutex
+	values map[string]string
+}
+
+// SetState sets a value, such as the ID of the opened server, that is included
+// in crash reports. An empty value removes it.
+func SetState(key, value string) {
+	state.Lock()
+	defer state.Unlock()
+
+	if state.values == nil {
+		state.values = map[string]string{}
+	}
+
+	if value == "" {
+		delete(state.values, key)
+	} else {
+		state.values[key] = value
+	}
+}
+
+// Recover writes a crash report if the current goroutine is panicking, then
+// continues panicking. It must be deferred directly.
+func Recover() {
+	if v := recover(); v != nil {
+		if _, ok := v.(reportedPanic); !ok {
+			report(v)
+		}
+
+		panic(v)
+	}
+}
+
+// Reported writes a crash report for v and returns the value to panic with, as
+// in panic(crash.Reported(v)). It is used in code that may run in Gtk signal
+// callbacks, where Recover can't be deferred.
+func Reported(v interface{}) interface{} {
+	report(v)
+	return reportedPanic{v}
+}
+
+// reportedPanic is the panic value returned by Reported, so that Recover
+// doesn't write a second report.
+type reportedPanic struct {
+	v interface{}
+}
+
+func (p reportedPanic) String() string {
+	return fmt.Sprint(p.v)
+}
+
+func report(v interface{}) {
+	path, err := WriteReport(v, debug.Stack())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to write crash report:", err)
+	} else {
+		fmt.Fprintln(os.Stderr, "Crash report written to", path)
+	}
+}
+
+// Go runs fn in a new goroutine with Recover deferred.
+func Go(fn func()) {
+	go func() {
+		defer Recover()
+		fn()
+	}()
+}
+
+// WriteReport writes a crash report for the given panic value and stack trace
+// into ReportDir. The path to the report is returned.
+func WriteReport(v interface{}, stack []byte) (string, error) {
+	dir := ReportDir()
+	if err := os.MkdirAll(dir, 0755|os.ModeDir); err != nil {
+		return "", errors.Wrap(err, "failed to make crash dir")
+	}
+
+	now := time.Now()
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "cchat-gtk crashed at %s.\n\n", now.Format(time.RFC3339))
+	fmt.Fprintf(&report, "Version: %s\n", version())
+	fmt.Fprintf(&report, "Go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	if name := profile.Name(); name != "" {
+		fmt.Fprintf(&report, "Profile: %s\n", name)
+	}
+
+	state.Lock()
+	var keys = make([]string, 0, len(state.values))
+	for k := range state.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&report, "%s: %s\n", k, state.values[k])
+	}
+	state.Unlock()
+
+	fmt.Fprintf(&report, "\nPanic: %v\n\n%s\n", v, stack)
+
+	entries := log.Entries()
+	if len(entries) > RecentEntries {
+		entries = entries[len(entries)-RecentEntries:]
+	}
+
+	report.WriteString("Recent log entries:\n\n")
+	for _, entry := range entries {
+		report.WriteString(entry.String())
+		report.WriteByte('\n')
+	}
+
+	f, err := createReport(dir, now)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create crash report")
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(report.String()); err != nil {
+		return f.Name(), errors.Wrap(err, "failed to write crash report")
+	}
+
+	return f.Name(), nil
+}
+
+// createReport creates a new report file named after the time. A number is
+// added to the name if a report from the same time already exists.
+func createReport(dir string, now time.Time) (*os.File, error) {
+	name := "crash-" + now.Format("20060102-150405.000000")
+
+	for i := 1; ; i++ {
+		path := filepath.Join(dir, name+reportExt)
+		if i > 1 {
+			path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", name, i, reportExt))
+		}
+
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if !os.IsExist(err) {
+			return f, err
+		}
+	}
+}
+
+func version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "" {
+		return "unknown"
+	}
+	return info.Main.Version
+}
+
+// UnseenReports returns the paths of crash reports that have not been marked as
+// seen, from oldest to newest.
+func UnseenReports() []string {
+	paths, _ := filepath.Glob(filepath.Join(ReportDir(), "crash-*"+reportExt))
+	sort.Strings(paths)
+	return paths
+}
+
+// MarkSeen marks the given report as seen, so it's not returned by
+// UnseenReports anymore. Only the latest MaxSeen seen reports are kept.
+func MarkSeen(path string) error {
+	if err := os.Rename(path, strings.TrimSuffix(path, reportExt)+seenExt); err != nil {
+		return err
+	}
+
+	seen, _ := filepath.Glob(filepath.Join(ReportDir(), "crash-*"+seenExt))
+	if len(seen) <= MaxSeen {
+		return nil
+	}
+
+	sort.Strings(seen)
+
+	for _, old := range seen[:len(seen)-MaxSeen] {
+		if err := os.Remove(old); err != nil {
+			return errors.Wrap(err, "failed to remove old crash report")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package crash
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cchat-gtk-crash")
+	if err != nil {
+		t.Fatal("Failed to make temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	old, hadOld := os.LookupEnv("XDG_CACHE_HOME")
+	os.Setenv("XDG_CACHE_HOME", dir)
+	defer func() {
+		if hadOld {
+			os.Setenv("XDG_CACHE_HOME", old)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+
+	var paths = map[string]bool{}
+	for i := 0; i < MaxSeen+5; i++ {
+		path, err := WriteReport("test", nil)
+		if err != nil {
+			t.Fatal("Failed to write report:", err)
+		}
+		if paths[path] {
+			t.Fatal("Report overwritten:", path)
+		}
+		paths[path] = true
+	}
+
+	unseen := UnseenReports()
+	if len(unseen) != len(paths) {
+		t.Fatalf("Expected %d unseen reports, got %d", len(paths), len(unseen))
+	}
+
+	for _, path := range unseen {
+		if err := MarkSeen(path); err != nil {
+			t.Fatal("Failed to mark report as seen:", err)
+		}
+	}
+
+	if unseen := UnseenReports(); len(unseen) != 0 {
+		t.Fatal("Unexpected unseen reports:", unseen)
+	}
+
+	seen, _ := filepath.Glob(filepath.Join(ReportDir(), "*"+seenExt))
+	if len(seen) != MaxSeen {
+		t.Fatalf("Expected %d seen reports, got %d", MaxSeen, len(seen))
+	}
+}
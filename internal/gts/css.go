@@ -35,3 +35,30 @@ func LoadCSS(name, css string) {
 
 	cssRepos[name] = prov
 }
+
+var userCSS *gtk.CssProvider
+
+// LoadUserCSS loads the given CSS with a higher priority than all built-in
+// styles, replacing the previously loaded user CSS. An empty string unloads it.
+// It must be called after the application is activated.
+func LoadUserCSS(name, css string) {
+	screen := getDefaultScreen()
+
+	if userCSS != nil {
+		gtk.RemoveProviderForScreen(screen, userCSS)
+		userCSS = nil
+	}
+
+	if css == "" {
+		return
+	}
+
+	prov, _ := gtk.CssProviderNew()
+	if err := prov.LoadFromData(css); err != nil {
+		log.Error(errors.Wrap(err, "Failed to parse CSS in "+name))
+		return
+	}
+
+	gtk.AddProviderForScreen(screen, prov, uint(gtk.STYLE_PROVIDER_PRIORITY_USER))
+	userCSS = prov
+}
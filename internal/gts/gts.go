@@ -6,8 +6,10 @@ import (
 	"os"
 	"time"
 
+	"github.com/diamondburned/cchat-gtk/internal/crash"
 	"github.com/diamondburned/cchat-gtk/internal/gts/throttler"
 	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/diamondburned/cchat-gtk/internal/profile"
 	"github.com/diamondburned/handy"
 	"github.com/gotk3/gotk3/gdk"
 	"github.com/gotk3/gotk3/glib"
@@ -15,9 +17,12 @@ import (
 	"github.com/pkg/errors"
 )
 
-const AppID = "com.github.diamondburned.cchat-gtk"
+// AppID is the application ID. It differs for each profile, so that multiple
+// profiles can run at the same time.
+var AppID = profile.AppID("com.github.diamondburned.cchat-gtk")
 
-var Args = append([]string{}, os.Args...)
+// Args contains the command-line arguments without the profile flags.
+var Args = profile.Args()
 
 var App struct {
 	*gtk.Application
@@ -32,6 +37,33 @@ func IsClosing() bool {
 	return App.closing
 }
 
+// quitStoppers are called before the window is closed.
+var quitStoppers []func() bool
+
+// OnQuit adds a function that's called when the user closes the window or
+// quits. If it returns true, the window is kept open, and the function is then
+// responsible for calling Quit again when appropriate.
+func OnQuit(fn func() bool) {
+	quitStoppers = append(quitStoppers, fn)
+}
+
+// Quit closes the main window, which quits the application, unless a function
+// added with OnQuit stops it.
+func Quit() {
+	if !stopQuit() {
+		App.Window.Destroy()
+	}
+}
+
+func stopQuit() bool {
+	for _, stop := range quitStoppers {
+		if stop() {
+			return true
+		}
+	}
+	return false
+}
+
 // Windower is the interface for a window.
 type Windower interface {
 	gtk.IWidget
@@ -142,8 +174,11 @@ func Main(wfn func() MainApplication) {
 			})
 		})
 
+		// Let OnQuit functions keep the window open.
+		App.Window.Window.Connect("delete-event", stopQuit)
+
 		// Connect extra actions.
-		AddAppAction("quit", App.Window.Destroy)
+		AddAppAction("quit", Quit)
 	})
 
 	// Use a special function to run the application. Exit with the appropriate
@@ -158,6 +193,8 @@ func Main(wfn func() MainApplication) {
 // TODO: deprecate Async.
 func Async(fn func() (func(), error)) {
 	go func() {
+		defer crash.Recover()
+
 		f, err := fn()
 		if err != nil {
 			log.Error(err)
@@ -175,6 +212,8 @@ func AsyncCancel(fn func(ctx context.Context) (func(), error)) context.CancelFun
 	ctx, cancel := context.WithCancel(context.Background())
 
 	go func() {
+		defer crash.Recover()
+
 		// fn() is assumed to use the same given ctx.
 		f, err := fn(ctx)
 		if err != nil {
@@ -194,6 +233,8 @@ func AsyncCancel(fn func(ctx context.Context) (func(), error)) context.CancelFun
 // executed if the given context has expired or the returned callback is called.
 func AsyncCtx(ctx context.Context, fn func() (func(), error)) {
 	go func() {
+		defer crash.Recover()
+
 		// fn() is assumed to use the same given ctx.
 		f, err := fn()
 		if err != nil {
@@ -209,13 +250,21 @@ func AsyncCtx(ctx context.Context, fn func() (func(), error)) {
 
 // ExecLater executes the function asynchronously with a low priority.
 func ExecLater(fn func()) {
-	glib.IdleAddPriority(glib.PRIORITY_DEFAULT_IDLE, fn)
+	glib.IdleAddPriority(glib.PRIORITY_DEFAULT_IDLE, recoverer(fn))
 }
 
 // ExecAsync executes function asynchronously in the Gtk main thread.
 // TODO: deprecate Async.
 func ExecAsync(fn func()) {
-	glib.IdleAddPriority(glib.PRIORITY_HIGH, fn)
+	glib.IdleAddPriority(glib.PRIORITY_HIGH, recoverer(fn))
+}
+
+// recoverer wraps fn to write a crash report if it panics.
+func recoverer(fn func()) func() {
+	return func() {
+		defer crash.Recover()
+		fn()
+	}
 }
 
 // ExecAsyncCtx executes the function asynchronously in the Gtk main thread only
@@ -0,0 +1,66 @@
+package httputil
+
+import (
+	"github.com/diamondburned/cchat-gtk/internal/gts"
+	"github.com/gotk3/gotk3/gdk"
+	"github.com/gotk3/gotk3/glib"
+)
+
+type playingAnimation struct {
+	anim    *gdk.PixbufAnimation
+	destroy glib.SignalHandle
+}
+
+// animations contains the containers showing animations. The animations are
+// swapped for their first frames while the window is unfocused, which saves CPU
+// time. It is only accessed from the main thread.
+var animations = struct {
+	m     map[ImageContainer]playingAnimation
+	bound bool
+}{
+	m: map[ImageContainer]playingAnimation{},
+}
+
+func setAnimation(img ImageContainer, anim *gdk.PixbufAnimation) {
+	throttler := gts.App.Throttler
+
+	if !animations.bound && throttler != nil {
+		animations.bound = true
+		throttler.OnChange(pauseAnimations)
+	}
+
+	playing, ok := animations.m[img]
+	if !ok {
+		playing.destroy = img.Connect("destroy", func() { delete(animations.m, img) })
+	}
+	playing.anim = anim
+	animations.m[img] = playing
+
+	if throttler != nil && throttler.IsThrottling() {
+		img.SetFromPixbuf(anim.GetStaticImage())
+	} else {
+		img.SetFromAnimation(anim)
+	}
+}
+
+// unsetAnimation stops tracking the container, such as when it's set to a
+// static image.
+func unsetAnimation(img ImageContainer) {
+	playing, ok := animations.m[img]
+	if !ok {
+		return
+	}
+
+	img.HandlerDisconnect(playing.destroy)
+	delete(animations.m, img)
+}
+
+func pauseAnimations(paused bool) {
+	for img, playing := range animations.m {
+		if paused {
+			img.SetFromPixbuf(playing.anim.GetStaticImage())
+		} else {
+			img.SetFromAnimation(playing.anim)
+		}
+	}
+}
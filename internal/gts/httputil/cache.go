@@ -0,0 +1,247 @@
+package httputil
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/pkg/errors"
+)
+
+// diskCache is an httpcache.Cache that stores responses in files and evicts the
+// least recently used ones once the total size exceeds the maximum. The access
+// time is kept in the file's modification time, so the order survives restarts.
+type diskCache struct {
+	dir string
+	max int64 // bytes
+
+	mutex sync.Mutex
+	lru   *list.List               // of *cacheEntry, most recent first
+	files map[string]*list.Element // name -> element
+	size  int64
+
+	hits   uint64
+	misses uint64
+}
+
+type cacheEntry struct {
+	name string
+	size int64
+}
+
+// CacheStats contains statistics of the media cache.
+type CacheStats struct {
+	Size    int64 // bytes
+	Entries int
+	Hits    uint64
+	Misses  uint64
+}
+
+// HitRate returns the ratio of requests served from the cache, from 0 to 1.
+func (s CacheStats) HitRate() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+func newDiskCache(dir string, max int64) *diskCache {
+	c := &diskCache{
+		dir:   dir,
+		max:   max,
+		lru:   list.New(),
+		files: map[string]*list.Element{},
+	}
+
+	if err := os.MkdirAll(dir, 0750|os.ModeDir); err != nil {
+		log.Error(errors.Wrap(err, "Failed to make media cache dir"))
+	}
+
+	c.scan()
+	return c
+}
+
+// scan loads the existing files into the LRU list, ordered by their
+// modification time.
+func (c *diskCache) scan() {
+	infos, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		log.Error(errors.Wrap(err, "Failed to read media cache dir"))
+		return
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().After(infos[j].ModTime())
+	})
+
+	for _, info := range infos {
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		// Clean up temporary files from interrupted writes.
+		if filepath.Ext(info.Name()) == ".tmp" {
+			os.Remove(filepath.Join(c.dir, info.Name()))
+			continue
+		}
+
+		c.files[info.Name()] = c.lru.PushBack(&cacheEntry{info.Name(), info.Size()})
+		c.size += info.Size()
+	}
+
+	c.evict()
+}
+
+func cacheName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *diskCache) path(name string) string {
+	return filepath.Join(c.dir, name)
+}
+
+func (c *diskCache) Get(key string) ([]byte, bool) {
+	name := cacheName(key)
+
+	c.mutex.Lock()
+	elem, ok := c.files[name]
+	if ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.mutex.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	b, err := ioutil.ReadFile(c.path(name))
+	if err != nil {
+		c.Delete(key)
+		return nil, false
+	}
+
+	// Persist the access order. This is best-effort.
+	now := time.Now()
+	os.Chtimes(c.path(name), now, now)
+
+	return b, true
+}
+
+func (c *diskCache) Set(key string, b []byte) {
+	name := cacheName(key)
+
+	f, err := ioutil.TempFile(c.dir, name+".*.tmp")
+	if err != nil {
+		log.Error(errors.Wrap(err, "Failed to create media cache file"))
+		return
+	}
+
+	_, err = f.Write(b)
+	f.Close()
+
+	if err == nil {
+		err = os.Rename(f.Name(), c.path(name))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		log.Error(errors.Wrap(err, "Failed to write media cache file"))
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.remove(name)
+	c.files[name] = c.lru.PushFront(&cacheEntry{name, int64(len(b))})
+	c.size += int64(len(b))
+
+	c.evict()
+}
+
+func (c *diskCache) Delete(key string) {
+	name := cacheName(key)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.remove(name) {
+		os.Remove(c.path(name))
+	}
+}
+
+// remove removes the entry from the index. The file is not deleted. It returns
+// false if there's no such entry.
+func (c *diskCache) remove(name string) bool {
+	elem, ok := c.files[name]
+	if !ok {
+		return false
+	}
+
+	c.size -= elem.Value.(*cacheEntry).size
+	c.lru.Remove(elem)
+	delete(c.files, name)
+
+	return true
+}
+
+// evict deletes the least recently used files until the cache fits.
+func (c *diskCache) evict() {
+	for c.size > c.max && c.lru.Len() > 0 {
+		entry := c.lru.Back().Value.(*cacheEntry)
+		c.remove(entry.name)
+		os.Remove(c.path(entry.name))
+	}
+}
+
+// SetMax changes the maximum size and evicts files if needed.
+func (c *diskCache) SetMax(max int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.max = max
+	c.evict()
+}
+
+// Clear deletes all cached files.
+func (c *diskCache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for name := range c.files {
+		os.Remove(c.path(name))
+	}
+
+	c.lru.Init()
+	c.files = map[string]*list.Element{}
+	c.size = 0
+}
+
+func (c *diskCache) countHit(hit bool) {
+	if hit {
+		atomic.AddUint64(&c.hits, 1)
+	} else {
+		atomic.AddUint64(&c.misses, 1)
+	}
+}
+
+// Stats returns the current statistics.
+func (c *diskCache) Stats() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return CacheStats{
+		Size:    c.size,
+		Entries: c.lru.Len(),
+		Hits:    atomic.LoadUint64(&c.hits),
+		Misses:  atomic.LoadUint64(&c.misses),
+	}
+}
@@ -7,36 +7,62 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/diamondburned/cchat-gtk/internal/offline"
+	"github.com/diamondburned/cchat-gtk/internal/profile"
+	"github.com/diamondburned/cchat-gtk/internal/ui/config"
 	"github.com/gregjones/httpcache"
-	"github.com/gregjones/httpcache/diskcache"
-	"github.com/peterbourgon/diskv"
 	"github.com/pkg/errors"
 )
 
-var basePath = filepath.Join(os.TempDir(), "cchat-gtk-caching-is-hard")
+// oldBasePath is where the media cache was stored before it was moved into the
+// cache directory.
+var oldBasePath = filepath.Join(os.TempDir(), profile.Namespace("cchat-gtk-caching-is-hard"))
+
+var basePath = filepath.Join(profile.CacheDir(), "media")
+
+// cacheSizeMiB is the maximum size of the media cache on disk.
+var cacheSizeMiB = 200
+
+var cache = newDiskCache(basePath, int64(cacheSizeMiB)<<20)
+
+func init() {
+	config.Register(config.Behavior, "Media Cache Size (MiB)", config.Describe(
+		"The maximum disk space used to cache avatars, emojis and images. "+
+			"The least recently used files are removed first.",
+		config.Spin(&cacheSizeMiB, 10, 10240, func(v int) { cache.SetMax(int64(v) << 20) }),
+	))
+
+	// Clean up the old cache, since it's never used again.
+	go os.RemoveAll(oldBasePath)
+}
 
 var dskcached = http.Client{
 	Timeout: 15 * time.Second,
 	Transport: &httpcache.Transport{
 		Transport: &http.Transport{
+			Proxy: Proxy,
 			// Be generous: use a 128KB buffer instead of 4KB to hopefully
 			// reduce cgo calls.
 			WriteBufferSize: 128 * 1024,
 			ReadBufferSize:  128 * 1024,
 		},
-		Cache: diskcache.NewWithDiskv(diskv.New(diskv.Options{
-			BasePath:     basePath,
-			TempDir:      filepath.Join(basePath, "tmp"),
-			PathPerm:     0750,
-			FilePerm:     0750,
-			Compression:  diskv.NewZlibCompressionLevel(4),
-			CacheSizeMax: 25 * 1024 * 1024, // 25 MiB in memory
-		})),
+		Cache:               cache,
 		MarkCachedResponses: true,
 	},
 }
 
-// TODO: log cache misses with httpcache.XFromCache
+// MediaCacheStats returns the statistics of the media cache since startup.
+func MediaCacheStats() CacheStats {
+	return cache.Stats()
+}
+
+// ClearMediaCache deletes all cached media from the disk.
+func ClearMediaCache() {
+	cache.Clear()
+}
+
+// ErrOffline is returned when a response is not cached while offline.
+var ErrOffline = errors.New("Not cached while offline")
 
 func get(ctx context.Context, url string, cached bool) (r *http.Response, err error) {
 	q, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -44,9 +70,22 @@ func get(ctx context.Context, url string, cached bool) (r *http.Response, err er
 		return nil, errors.Wrap(err, "Failed to make a request")
 	}
 
-	r, err = dskcached.Do(q)
-	if err != nil {
-		return nil, err
+	// Only serve from the cache while offline, so nothing waits for a timeout.
+	if offline.IsOffline() {
+		r, err = httpcache.CachedResponse(cache, q)
+		if err != nil || r == nil {
+			cache.countHit(false)
+			return nil, ErrOffline
+		}
+
+		cache.countHit(true)
+	} else {
+		r, err = dskcached.Do(q)
+		if err != nil {
+			return nil, err
+		}
+
+		cache.countHit(r.Header.Get(httpcache.XFromCache) == "1")
 	}
 
 	if r.StatusCode < 200 || r.StatusCode > 299 {
@@ -8,10 +8,13 @@ import (
 	"net/url"
 	"path"
 	"strings"
+	"sync"
 
+	"github.com/diamondburned/cchat-gtk/internal/crash"
 	"github.com/diamondburned/cchat-gtk/internal/gts"
 	"github.com/diamondburned/cchat-gtk/internal/log"
 	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
+	"github.com/diamondburned/cchat-gtk/internal/ui/primitives/animation"
 	"github.com/diamondburned/imgutil"
 	"github.com/gotk3/gotk3/cairo"
 	"github.com/gotk3/gotk3/gdk"
@@ -50,6 +53,10 @@ func (wrapper *surfaceWrapper) SetFromPixbuf(pb *gdk.Pixbuf) {
 
 // AsyncImage loads an image. This method uses the cache. It prefers loading
 // SetFromSurface over SetFromPixbuf, but will fallback if needed be.
+//
+// Decoded images are kept in memory, and concurrent requests for the same URL,
+// size and scale share a single fetch and decode. Processors are closures that
+// can't be compared, so images with processors are neither cached nor shared.
 func AsyncImage(ctx context.Context,
 	img ImageContainer, imageURL string, procs ...imgutil.Processor) {
 
@@ -65,75 +72,356 @@ func AsyncImage(ctx context.Context,
 		scale = surfaceContainer.GetScaleFactor()
 	}
 
+	key := imageKey{imageURL, w, h, scale}
+	set := imageSetter(img, scale)
+
+	if len(procs) == 0 {
+		if image, ok := getDecoded(key); ok {
+			set(image)
+			return
+		}
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 	cancelHandle := img.Connect("destroy", func() {
 		log.Println("image destroyed, canceling")
 		cancel()
 	})
 
-	go func() {
-		// Ensure the contexts are cleaned up in the main thread.
-		defer gts.ExecAsync(func() {
+	joinFlight(key, procs, &imageWaiter{
+		ctx:     ctx,
+		set:     set,
+		visible: func() bool { return widgetVisible(img) },
+		done: func() {
 			img.HandlerDisconnect(cancelHandle)
 			cancel()
-		})
+		},
+	})
+}
 
-		// Try and guess the MIME type from the URL.
-		mimeType := mime.TypeByExtension(urlExt(imageURL))
+// imageSetter returns a function that sets the decoded image into the given
+// container. Only bother with surfaces if we even have HiDPI.
+func imageSetter(img ImageContainer, scale int) func(decodedImage) {
+	var pixbufSetter = img
+	if surfaceContainer, ok := img.(SurfaceContainer); ok && scale > 1 {
+		pixbufSetter = &surfaceWrapper{surfaceContainer, scale}
+	}
 
-		r, err := get(ctx, imageURL, true)
-		if err != nil {
-			log.Error(errors.Wrap(err, "failed to GET"))
-			return
+	return func(image decodedImage) {
+		switch image := image.(type) {
+		case *gdk.Pixbuf:
+			unsetAnimation(img)
+			pixbufSetter.SetFromPixbuf(image)
+		case unscaledPixbuf:
+			unsetAnimation(img)
+			img.SetFromPixbuf(image.Pixbuf)
+		case *gdk.PixbufAnimation:
+			setAnimation(img, image)
 		}
-		defer r.Body.Close()
+	}
+}
 
-		// Try and use the image type from the MIME header over the type from
-		// the URL, as it is more reliable.
-		if mime := mimeFromHeaders(r.Header); mime != "" {
-			mimeType = mime
-		}
+// imageWaiter is a widget waiting for an image. All its callbacks are called in
+// the main thread.
+type imageWaiter struct {
+	ctx     context.Context
+	set     func(decodedImage)
+	visible func() bool
+	done    func()
+}
 
-		_, fileType := path.Split(mimeType) // abuse split "a/b" to get b
+// imageFlight is a fetch and decode shared by all waiters of the same image. It
+// is canceled once all of its waiters are gone.
+type imageFlight struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters map[*imageWaiter]struct{}
+	last    decodedImage // the partially decoded image so far
+}
+
+var flights = struct {
+	sync.Mutex
+	m map[imageKey]*imageFlight
+}{
+	m: map[imageKey]*imageFlight{},
+}
+
+// joinFlight adds the waiter to the in-flight request of the same key, or
+// starts a new one if there's none. It must be called in the main thread.
+func joinFlight(key imageKey, procs []imgutil.Processor, w *imageWaiter) {
+	shared := len(procs) == 0
 
-		isGIF := fileType == "gif"
-		if isGIF {
-			canSurface = false
-			scale = 1
+	flights.Lock()
+
+	f, ok := flights.m[key]
+	if !shared || !ok || f.ctx.Err() != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		f = &imageFlight{
+			ctx:     ctx,
+			cancel:  cancel,
+			waiters: map[*imageWaiter]struct{}{},
+		}
+		if shared {
+			flights.m[key] = f
 		}
 
-		// Only bother with this if we even have HiDPI. We also can't use a
-		// Surface for a GIF.
-		if canSurface && scale > 1 {
-			img = &surfaceWrapper{surfaceContainer, scale}
+		go f.run(key, procs, shared)
+	}
+
+	f.waiters[w] = struct{}{}
+	last := f.last
+
+	flights.Unlock()
+
+	// Show what's been decoded so far.
+	if last != nil {
+		w.set(last)
+	}
+
+	go f.watch(w)
+}
+
+// watch removes the waiter once it's canceled, and cancels the flight if no
+// one else is waiting for it.
+func (f *imageFlight) watch(w *imageWaiter) {
+	<-w.ctx.Done()
+
+	flights.Lock()
+	defer flights.Unlock()
+
+	if _, ok := f.waiters[w]; !ok {
+		return
+	}
+
+	delete(f.waiters, w)
+	if len(f.waiters) == 0 {
+		f.cancel()
+	}
+}
+
+// update sets the partially decoded image into all waiters.
+func (f *imageFlight) update(image decodedImage) {
+	flights.Lock()
+	f.last = image
+	waiters := f.waiterList()
+	flights.Unlock()
+
+	gts.ExecAsync(func() {
+		for _, w := range waiters {
+			if w.ctx.Err() == nil {
+				w.set(image)
+			}
 		}
+	})
+}
 
-		l, err := gdk.PixbufLoaderNewWithType(fileType)
-		if err != nil {
-			log.Error(errors.Wrapf(err, "failed to make PixbufLoader type %q", fileType))
-			return
+// visible returns true if any of the waiters is visible. It must be called in
+// the main thread.
+func (f *imageFlight) visible() bool {
+	flights.Lock()
+	waiters := f.waiterList()
+	flights.Unlock()
+
+	for _, w := range waiters {
+		if w.ctx.Err() == nil && w.visible() {
+			return true
 		}
+	}
+	return false
+}
 
-		l.Connect("size-prepared", func(l *gdk.PixbufLoader, imgW, imgH int) {
-			w, h = imgutil.MaxSize(imgW, imgH, w, h)
-			if w != imgW || h != imgH || scale > 1 {
-				l.SetSize(w*scale, h*scale)
+// waiterList returns the current waiters. The mutex must be acquired.
+func (f *imageFlight) waiterList() []*imageWaiter {
+	var waiters = make([]*imageWaiter, 0, len(f.waiters))
+	for w := range f.waiters {
+		waiters = append(waiters, w)
+	}
+	return waiters
+}
+
+func (f *imageFlight) run(key imageKey, procs []imgutil.Processor, shared bool) {
+	defer crash.Recover()
+
+	image, err := decodeImage(f.ctx, key, procs, f.visible, f.update)
+	if err != nil && f.ctx.Err() == nil && errors.Cause(err) != ErrOffline {
+		log.Error(err)
+	}
+
+	flights.Lock()
+	if shared && flights.m[key] == f {
+		delete(flights.m, key)
+	}
+	waiters := f.waiterList()
+	flights.Unlock()
+
+	// Don't cache partial images.
+	if err == nil && shared && image != nil {
+		putDecoded(key, image)
+	}
+
+	// Ensure the contexts are cleaned up in the main thread.
+	gts.ExecAsync(func() {
+		for _, w := range waiters {
+			if image != nil && w.ctx.Err() == nil {
+				w.set(image)
 			}
-		})
+			w.done()
+		}
+
+		f.cancel()
+	})
+}
 
-		load := loadFn(ctx, img, isGIF)
-		l.Connect("area-prepared", load)
-		l.Connect("area-updated", load)
+// decodeImage fetches and decodes the image once the scheduler allows it, which
+// prioritizes images that are visible. The update callback is called with
+// the partially decoded image as it is being decoded. The image is returned
+// even if there's an error, in which case it may be partial or nil.
+func decodeImage(ctx context.Context,
+	key imageKey, procs []imgutil.Processor,
+	visible func() bool, update func(decodedImage)) (decodedImage, error) {
 
-		if err := downloadImage(r.Body, l, procs, isGIF); err != nil {
-			log.Error(errors.Wrapf(err, "failed to download %q", imageURL))
-			// Force close after downloading.
+	var w, h, scale = key.w, key.h, key.scale
+
+	// Try and guess the MIME type from the URL.
+	mimeType := mime.TypeByExtension(urlExt(key.url))
+
+	release, err := acquireLoad(ctx, key.url, visible)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	r, err := get(ctx, key.url, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to GET")
+	}
+	defer r.Body.Close()
+
+	// Try and use the image type from the MIME header over the type from
+	// the URL, as it is more reliable.
+	if mime := mimeFromHeaders(r.Header); mime != "" {
+		mimeType = mime
+	}
+
+	// We can't use a Surface for an animation, so images that may be animated
+	// are decoded at scale 1. The still ones are then marked as unscaled, so
+	// that they're not shrunk by the surface scale.
+	if animatedTypes[mimeType] && scale > 1 {
+		scale = 1
+		update = unscaledUpdate(update)
+	}
+
+	// Processed images are re-encoded, so the loader has to read the processed
+	// format instead.
+	if len(procs) > 0 {
+		switch {
+		case mimeType == "image/gif":
+			// imgutil keeps GIFs animated.
+		case animatedTypes[mimeType]:
+			// imgutil either can't decode these or would only keep their first
+			// frame, so they're loaded unprocessed to keep them animated.
+			procs = nil
+		default:
+			mimeType = "image/png"
 		}
+	}
 
-		if err := l.Close(); err != nil {
-			log.Error(errors.Wrapf(err, "failed to close pixbuf loader for %q", imageURL))
+	l, err := newLoader(mimeType)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to make PixbufLoader for %q", mimeType)
+	}
+
+	l.Connect("size-prepared", func(l *gdk.PixbufLoader, imgW, imgH int) {
+		w, h = imgutil.MaxSize(imgW, imgH, w, h)
+		if w != imgW || h != imgH || scale > 1 {
+			l.SetSize(w*scale, h*scale)
 		}
-	}()
+	})
+
+	load := loadFn(update)
+	l.Connect("area-prepared", load)
+	l.Connect("area-updated", load)
+
+	// Force close after downloading.
+	err = downloadImage(r.Body, l, procs, animatedTypes[mimeType])
+	if err != nil {
+		err = errors.Wrapf(err, "failed to download %q", key.url)
+	}
+
+	if closeErr := l.Close(); closeErr != nil && err == nil {
+		err = errors.Wrapf(closeErr, "failed to close pixbuf loader for %q", key.url)
+	}
+
+	// Only use the animation if it actually has more than one frame, which
+	// works for any format that gdk-pixbuf can animate.
+	if anim, animErr := l.GetAnimation(); animErr == nil && !animation.IsStatic(anim) {
+		return anim, err
+	}
+
+	image := loadedPixbuf(l)
+	if pixbuf, ok := image.(*gdk.Pixbuf); ok && scale != key.scale {
+		image = unscaledPixbuf{pixbuf}
+	}
+
+	return image, err
+}
+
+// unscaledUpdate wraps the update callback to mark the partially decoded
+// pixbufs as unscaled.
+func unscaledUpdate(update func(decodedImage)) func(decodedImage) {
+	return func(image decodedImage) {
+		if pixbuf, ok := image.(*gdk.Pixbuf); ok {
+			image = unscaledPixbuf{pixbuf}
+		}
+		update(image)
+	}
+}
+
+// animatedTypes are the MIME types of images that may be animated. They are
+// loaded at scale 1.
+var animatedTypes = map[string]bool{
+	"image/gif":  true,
+	"image/webp": true,
+	"image/apng": true,
+}
+
+// loaderTypes maps MIME types to the names of the gdk-pixbuf loaders.
+var loaderTypes struct {
+	sync.Once
+	m map[string]string
+}
+
+// newLoader creates a PixbufLoader for the given MIME type. The format is
+// guessed from the data if no loader claims the MIME type.
+func newLoader(mimeType string) (*gdk.PixbufLoader, error) {
+	loaderTypes.Do(func() {
+		loaderTypes.m = map[string]string{}
+
+		for _, format := range gdk.PixbufGetFormats() {
+			name, err := format.GetName()
+			if err != nil {
+				continue
+			}
+			for _, mimeType := range format.GetMimeTypes() {
+				loaderTypes.m[mimeType] = name
+			}
+		}
+	})
+
+	if name, ok := loaderTypes.m[mimeType]; ok {
+		return gdk.PixbufLoaderNewWithType(name)
+	}
+
+	return gdk.PixbufLoaderNew()
+}
+
+// loadedPixbuf returns the loader's pixbuf, or nil if there's none yet. A nil
+// interface is returned instead of a typed nil pointer.
+func loadedPixbuf(l *gdk.PixbufLoader) decodedImage {
+	if pixbuf, err := l.GetPixbuf(); err == nil {
+		return pixbuf
+	}
+	return nil
 }
 
 func urlExt(anyURL string) string {
@@ -157,43 +445,28 @@ func mimeFromHeaders(headers http.Header) string {
 	return media
 }
 
-func loadFn(ctx context.Context, img ImageContainer, isGIF bool) func(l *gdk.PixbufLoader) {
-	var pixbuf interface{}
+// loadFn returns a callback that shows the partially loaded image. Animations
+// only show their first frame until they're fully loaded.
+func loadFn(update func(decodedImage)) func(l *gdk.PixbufLoader) {
+	var image decodedImage
 
 	return func(l *gdk.PixbufLoader) {
-		if pixbuf == nil {
-			if !isGIF {
-				pixbuf, _ = l.GetPixbuf()
-			} else {
-				pixbuf, _ = l.GetAnimation()
-			}
+		if image == nil {
+			image = loadedPixbuf(l)
 		}
 
-		switch pixbuf := pixbuf.(type) {
-		case *gdk.Pixbuf:
-			execIfCtx(ctx, func() { img.SetFromPixbuf(pixbuf) })
-		case *gdk.PixbufAnimation:
-			execIfCtx(ctx, func() { img.SetFromAnimation(pixbuf) })
+		if image != nil {
+			update(image)
 		}
 	}
 }
 
-func execIfCtx(ctx context.Context, fn func()) {
-	if ctx.Err() == nil {
-		gts.ExecAsync(func() {
-			if ctx.Err() == nil {
-				fn()
-			}
-		})
-	}
-}
-
-func downloadImage(src io.Reader, dst io.Writer, p []imgutil.Processor, isGIF bool) error {
+func downloadImage(src io.Reader, dst io.Writer, p []imgutil.Processor, animated bool) error {
 	var err error
 
 	// If we have processors, then write directly in there.
 	if len(p) > 0 {
-		if !isGIF {
+		if !animated {
 			err = imgutil.ProcessStream(dst, src, p)
 		} else {
 			err = imgutil.ProcessAnimationStream(dst, src, p)
@@ -0,0 +1,101 @@
+package httputil
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/gotk3/gotk3/gdk"
+)
+
+// MaxDecodedSize is the maximum total size in bytes of the decoded images kept
+// in memory.
+const MaxDecodedSize = 64 * 1024 * 1024
+
+// imageKey identifies a decoded image. Images of the same URL are decoded
+// differently depending on the requested size and scale.
+type imageKey struct {
+	url   string
+	w, h  int
+	scale int
+}
+
+// decodedImage is either a *gdk.Pixbuf, an unscaledPixbuf or a
+// *gdk.PixbufAnimation. Animations are never cached.
+type decodedImage interface{}
+
+// unscaledPixbuf is a pixbuf decoded at scale 1 although a larger scale was
+// requested, which happens to still images in formats that may be animated.
+// It is set as-is instead of through a scaled surface.
+type unscaledPixbuf struct {
+	*gdk.Pixbuf
+}
+
+type decodedEntry struct {
+	key   imageKey
+	image decodedImage
+	size  int
+}
+
+// decodedCache is an LRU of decoded images, so the same avatar or emoji shown
+// in many widgets is only fetched and decoded once.
+var decodedCache = struct {
+	sync.Mutex
+	lru     *list.List // of *decodedEntry, most recent first
+	entries map[imageKey]*list.Element
+	size    int
+}{
+	lru:     list.New(),
+	entries: map[imageKey]*list.Element{},
+}
+
+func getDecoded(key imageKey) (decodedImage, bool) {
+	decodedCache.Lock()
+	defer decodedCache.Unlock()
+
+	elem, ok := decodedCache.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	decodedCache.lru.MoveToFront(elem)
+	return elem.Value.(*decodedEntry).image, true
+}
+
+func putDecoded(key imageKey, image decodedImage) {
+	size := decodedSize(image)
+	if size <= 0 || size > MaxDecodedSize {
+		return
+	}
+
+	decodedCache.Lock()
+	defer decodedCache.Unlock()
+
+	if elem, ok := decodedCache.entries[key]; ok {
+		decodedCache.size -= elem.Value.(*decodedEntry).size
+		decodedCache.lru.Remove(elem)
+	}
+
+	entry := &decodedEntry{key, image, size}
+	decodedCache.entries[key] = decodedCache.lru.PushFront(entry)
+	decodedCache.size += size
+
+	for decodedCache.size > MaxDecodedSize {
+		last := decodedCache.lru.Back().Value.(*decodedEntry)
+		decodedCache.size -= last.size
+		decodedCache.lru.Remove(decodedCache.lru.Back())
+		delete(decodedCache.entries, last.key)
+	}
+}
+
+// decodedSize returns the memory used by the image, or 0 if it can't be
+// cached. Animations keep all of their frames decoded, but their frames can't
+// be counted, so they're left out to keep MaxDecodedSize a real bound.
+func decodedSize(image decodedImage) int {
+	switch image := image.(type) {
+	case *gdk.Pixbuf:
+		return image.GetByteLength()
+	case unscaledPixbuf:
+		return image.GetByteLength()
+	}
+	return 0
+}
@@ -0,0 +1,175 @@
+package httputil
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/diamondburned/cchat-gtk/internal/ui/config"
+	"github.com/pkg/errors"
+)
+
+// Proxy modes.
+const (
+	ProxyDirect      = "Direct"
+	ProxyEnvironment = "Environment"
+	ProxyHTTP        = "HTTP"
+	ProxyHTTPS       = "HTTPS"
+	ProxySOCKS5      = "SOCKS5"
+)
+
+var proxySchemes = map[string]string{
+	ProxyHTTP:   "http",
+	ProxyHTTPS:  "https",
+	ProxySOCKS5: "socks5",
+}
+
+// proxyConfig is bound to the config entries, so it's only accessed from the
+// main thread.
+var proxyConfig = struct {
+	mode     string
+	host     string
+	username string
+	password string
+	noProxy  []string
+}{
+	mode: ProxyEnvironment,
+}
+
+// proxyState is the proxy derived from proxyConfig, which is safe to use from
+// any goroutine. It starts out matching the default mode.
+var proxyState = struct {
+	sync.RWMutex
+	env     bool
+	url     *url.URL // nil if none
+	noProxy []string
+}{
+	env: true,
+}
+
+func init() {
+	c := &proxyConfig
+
+	config.Register(config.Network, "Proxy", config.Describe(
+		"The proxy for media requests and the services' HTTP requests. "+
+			"Environment uses $HTTPS_PROXY and the like, and Direct connects "+
+			"without any proxy.",
+		config.Choice(&c.mode, []string{
+			ProxyEnvironment, ProxyDirect, ProxyHTTP, ProxyHTTPS, ProxySOCKS5,
+		}, func(string) error {
+			// Errors are shown on the host instead, since it's the one that
+			// needs fixing.
+			updateProxy()
+			return nil
+		}),
+	))
+	config.Register(config.Network, "Proxy Host", config.Describe(
+		"The proxy address as host:port.",
+		config.InputEntry(&c.host, func(string) error { return updateProxy() }),
+	))
+	config.Register(config.Network, "Proxy Username", config.Describe(
+		"Optional.",
+		config.InputEntry(&c.username, func(string) error { return updateProxy() }),
+	))
+	config.Register(config.Network, "Proxy Password", config.Describe(
+		"Optional. This is stored in plain text in the config file, but "+
+			"it's left out of exported profiles.",
+		config.PasswordEntry(&c.password, func(string) error { return updateProxy() }),
+	))
+	config.Register(config.Network, "No Proxy", config.Describe(
+		"Hosts that are connected to directly, such as localhost, "+
+			"example.com (including subdomains) or 10.0.0.0/8.",
+		config.StringList(&c.noProxy, nil, func([]string) { updateProxy() }),
+	))
+
+	// Backends that use the default transport are proxied the same way. The
+	// proxy isn't exported through the environment, since that would leak the
+	// credentials to child processes and can't be changed afterwards.
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		t.Proxy = Proxy
+	}
+}
+
+// updateProxy rebuilds the proxy state from the config values.
+func updateProxy() error {
+	c := &proxyConfig
+
+	proxyState.Lock()
+	defer proxyState.Unlock()
+
+	proxyState.env = c.mode == ProxyEnvironment
+	proxyState.url = nil
+	proxyState.noProxy = c.noProxy
+
+	scheme, ok := proxySchemes[c.mode]
+	if !ok {
+		return nil
+	}
+
+	if c.host == "" {
+		return errors.New("missing proxy host")
+	}
+
+	if _, _, err := net.SplitHostPort(c.host); err != nil {
+		return errors.Wrap(err, "invalid proxy host")
+	}
+
+	u := &url.URL{Scheme: scheme, Host: c.host}
+	if c.username != "" {
+		u.User = url.UserPassword(c.username, c.password)
+	}
+
+	proxyState.url = u
+
+	return nil
+}
+
+// Proxy returns the proxy URL for the given request according to the
+// settings. It has the signature of http.Transport's Proxy field.
+func Proxy(r *http.Request) (*url.URL, error) {
+	proxyState.RLock()
+	defer proxyState.RUnlock()
+
+	switch {
+	case proxyState.env:
+		return http.ProxyFromEnvironment(r)
+	case proxyState.url == nil:
+		return nil, nil
+	case bypassProxy(r.URL.Hostname(), proxyState.noProxy):
+		return nil, nil
+	default:
+		return proxyState.url, nil
+	}
+}
+
+// bypassProxy returns true if the host matches any pattern in the no-proxy
+// list.
+func bypassProxy(host string, noProxy []string) bool {
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+
+	for _, pattern := range noProxy {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+
+		switch {
+		case pattern == "*":
+			return true
+
+		case strings.Contains(pattern, "/"):
+			_, cidr, err := net.ParseCIDR(pattern)
+			if err == nil && ip != nil && cidr.Contains(ip) {
+				return true
+			}
+
+		default:
+			pattern = strings.TrimPrefix(pattern, ".")
+			if host == pattern || strings.HasSuffix(host, "."+pattern) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,210 @@
+package httputil
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/diamondburned/cchat-gtk/internal/gts"
+	"github.com/gotk3/gotk3/gtk"
+)
+
+const (
+	// MaxConcurrentLoads is the maximum number of images fetched at once.
+	MaxConcurrentLoads = 8
+	// MaxHostLoads is the maximum number of images fetched at once from a
+	// single host.
+	MaxHostLoads = 4
+)
+
+// loadTask is a fetch waiting for a slot.
+type loadTask struct {
+	host    string
+	visible func() bool // called in the main thread
+	start   chan struct{}
+}
+
+// scheduler limits the number of concurrent fetches. Pending fetches of visible
+// widgets are started first, and the visibility is checked again every time a
+// slot is freed, so widgets that scroll out of view are deprioritized.
+var scheduler = struct {
+	sync.Mutex
+	pending  []*loadTask // FIFO
+	running  int
+	hosts    map[string]int
+	dispatch bool // true if a dispatch is queued
+}{
+	hosts: map[string]int{},
+}
+
+// acquireLoad blocks until a slot for fetching the given URL is free, or until
+// the context is canceled. The returned callback must be called to give the
+// slot back.
+func acquireLoad(ctx context.Context, rawURL string, visible func() bool) (release func(), err error) {
+	var host string
+	if u, err := url.Parse(rawURL); err == nil {
+		host = u.Host
+	}
+
+	t := &loadTask{
+		host:    host,
+		visible: visible,
+		start:   make(chan struct{}),
+	}
+
+	scheduler.Lock()
+	scheduler.pending = append(scheduler.pending, t)
+	scheduler.Unlock()
+
+	queueDispatch()
+
+	release = func() { releaseLoad(t) }
+
+	select {
+	case <-t.start:
+		return release, nil
+	case <-ctx.Done():
+	}
+
+	scheduler.Lock()
+	removed := removePending(t)
+	scheduler.Unlock()
+
+	// The task was started right as it was canceled, so give the slot back.
+	if !removed {
+		release()
+	}
+
+	return nil, ctx.Err()
+}
+
+func releaseLoad(t *loadTask) {
+	scheduler.Lock()
+
+	scheduler.running--
+	if scheduler.hosts[t.host]--; scheduler.hosts[t.host] <= 0 {
+		delete(scheduler.hosts, t.host)
+	}
+
+	scheduler.Unlock()
+
+	queueDispatch()
+}
+
+// removePending removes the task from the queue. It returns false if the task
+// is not pending anymore. The mutex must be acquired.
+func removePending(t *loadTask) bool {
+	for i, pending := range scheduler.pending {
+		if pending == t {
+			scheduler.pending = append(scheduler.pending[:i], scheduler.pending[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// queueDispatch queues a dispatch in the main thread, unless one is already
+// queued.
+func queueDispatch() {
+	scheduler.Lock()
+	defer scheduler.Unlock()
+
+	if scheduler.dispatch {
+		return
+	}
+	scheduler.dispatch = true
+
+	gts.ExecAsync(dispatchLoads)
+}
+
+// dispatchLoads starts as many pending tasks as the limits allow. It must be
+// called in the main thread, since the visibility checks touch widgets.
+func dispatchLoads() {
+	scheduler.Lock()
+	scheduler.dispatch = false
+
+	if scheduler.running >= MaxConcurrentLoads || len(scheduler.pending) == 0 {
+		scheduler.Unlock()
+		return
+	}
+
+	tasks := make([]*loadTask, len(scheduler.pending))
+	copy(tasks, scheduler.pending)
+	scheduler.Unlock()
+
+	// Check the visibility outside the lock, as it calls into GTK.
+	visible := make(map[*loadTask]bool, len(tasks))
+	for _, t := range tasks {
+		visible[t] = t.visible == nil || t.visible()
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return visible[tasks[i]] && !visible[tasks[j]]
+	})
+
+	scheduler.Lock()
+	defer scheduler.Unlock()
+
+	for _, t := range tasks {
+		if scheduler.running >= MaxConcurrentLoads {
+			break
+		}
+		if scheduler.hosts[t.host] >= MaxHostLoads {
+			continue
+		}
+		// Skip tasks canceled in the meantime.
+		if !removePending(t) {
+			continue
+		}
+
+		scheduler.running++
+		scheduler.hosts[t.host]++
+		close(t.start)
+	}
+}
+
+// widgetVisible returns true if the image is mapped and within the visible area
+// of its scrolled window, if any. Images that aren't widgets are always
+// visible.
+func widgetVisible(img ImageContainer) bool {
+	iw, ok := img.(gtk.IWidget)
+	if !ok {
+		return true
+	}
+
+	w := iw.ToWidget()
+	if !w.GetMapped() {
+		return false
+	}
+
+	sw := scrolledParent(w)
+	if sw == nil {
+		return true
+	}
+
+	x, y, err := w.TranslateCoordinates(sw, 0, 0)
+	if err != nil {
+		return true
+	}
+
+	inX := x+w.GetAllocatedWidth() > 0 && x < sw.GetAllocatedWidth()
+	inY := y+w.GetAllocatedHeight() > 0 && y < sw.GetAllocatedHeight()
+
+	return inX && inY
+}
+
+func scrolledParent(w *gtk.Widget) *gtk.ScrolledWindow {
+	for {
+		parent, err := w.GetParent()
+		if err != nil || parent == nil {
+			return nil
+		}
+
+		if sw, ok := parent.(*gtk.ScrolledWindow); ok {
+			return sw
+		}
+
+		w = parent.ToWidget()
+	}
+}
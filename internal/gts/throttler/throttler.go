@@ -13,6 +13,9 @@ type State struct {
 	throttling bool
 	ticker     <-chan time.Time
 	settings   *gtk.Settings
+
+	handlers map[int]func(throttling bool)
+	serial   int
 }
 
 type Connector interface {
@@ -24,6 +27,7 @@ func Bind(app *gtk.Application) *State {
 	var s = State{
 		settings: settings,
 		ticker:   time.Tick(time.Second / TPS),
+		handlers: map[int]func(bool){},
 	}
 
 	app.Connect("window-added", func(app *gtk.Application, w *gtk.Window) {
@@ -38,6 +42,28 @@ func (s *State) Connect(c Connector) {
 	c.Connect("focus-in-event", func(interface{}) { s.Stop() })
 }
 
+// IsThrottling returns true if the window is unfocused.
+func (s *State) IsThrottling() bool {
+	return s.throttling
+}
+
+// OnChange adds a callback that is called when throttling starts or stops, so
+// that things such as animations can be paused. The returned callback removes
+// it.
+func (s *State) OnChange(f func(throttling bool)) (remove func()) {
+	id := s.serial
+	s.serial++
+	s.handlers[id] = f
+
+	return func() { delete(s.handlers, id) }
+}
+
+func (s *State) changed() {
+	for _, f := range s.handlers {
+		f(s.throttling)
+	}
+}
+
 func (s *State) Start() {
 	if s.throttling {
 		return
@@ -45,6 +71,7 @@ func (s *State) Start() {
 
 	s.throttling = true
 	s.settings.SetProperty("gtk-enable-animations", false)
+	s.changed()
 
 	glib.IdleAdd(func() bool {
 		// Throttle.
@@ -68,4 +95,5 @@ func (s *State) Stop() {
 
 	s.throttling = false
 	s.settings.SetProperty("gtk-enable-animations", true)
+	s.changed()
 }
@@ -4,11 +4,25 @@ import (
 	"fmt"
 
 	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/pkg/errors"
 )
 
+// ErrExists is returned by Migrate if the destination provider already holds
+// secrets for the service.
+var ErrExists = errors.New("destination already holds the secrets")
+
 type Provider interface {
+	// Name returns the human-readable name of the provider.
+	Name() string
+
 	Get(service string, v interface{}) error
 	Set(service string, v interface{}) error
+	// Delete deletes the secrets of the given service. Deleting a service that
+	// does not exist is not an error.
+	Delete(service string) error
+	// Contains returns true if the provider holds secrets for the given
+	// service.
+	Contains(service string) (bool, error)
 }
 
 type Store struct {
@@ -19,6 +33,60 @@ func NewStore(providers ...Provider) Store {
 	return Store{providers}
 }
 
+// Providers returns the list of providers in the order that they are tried.
+func (s Store) Providers() []Provider {
+	return s.providers
+}
+
+// Locate returns all providers that hold secrets for the given service. The
+// first provider is the one that Get will read from. Providers that fail to be
+// checked are skipped.
+func (s Store) Locate(service string) []Provider {
+	var holders []Provider
+
+	for _, provider := range s.providers {
+		ok, err := provider.Contains(service)
+		if err != nil {
+			log.Info(errors.Wrapf(err, "failed to check %s", provider.Name()))
+			continue
+		}
+		if ok {
+			holders = append(holders, provider)
+		}
+	}
+
+	return holders
+}
+
+// Migrate moves the secrets of the given service from one provider to
+// another, then deletes the stale copy. v is used as the intermediate value,
+// so it must be a pointer to the type that the secrets were saved as. Secrets
+// that the destination already holds are never overwritten; ErrExists is
+// returned instead, and both copies are left as-is.
+func (s Store) Migrate(service string, from, to Provider, v interface{}) error {
+	exists, err := to.Contains(service)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check %s", to.Name())
+	}
+	if exists {
+		return ErrExists
+	}
+
+	if err := from.Get(service, v); err != nil {
+		return errors.Wrapf(err, "failed to read from %s", from.Name())
+	}
+
+	if err := to.Set(service, v); err != nil {
+		return errors.Wrapf(err, "failed to write to %s", to.Name())
+	}
+
+	if err := from.Delete(service); err != nil {
+		return errors.Wrapf(err, "failed to delete from %s", from.Name())
+	}
+
+	return nil
+}
+
 func (s Store) Get(service string, v interface{}) error {
 	for _, provider := range s.providers {
 		if err := provider.Get(service, v); err == nil {
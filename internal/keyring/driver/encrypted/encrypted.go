@@ -0,0 +1,450 @@
+// Package encrypted provides a keyring provider that stores secrets in files
+// encrypted with a key derived from a user passphrase.
+package encrypted
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/diamondburned/cchat-gtk/internal/keyring/driver"
+	jsondriver "github.com/diamondburned/cchat-gtk/internal/keyring/driver/json"
+	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+var (
+	// ErrLocked is returned by Get and Set if the provider has not been
+	// unlocked with a passphrase yet.
+	ErrLocked = errors.New("encrypted keyring is locked")
+	// ErrWrongPassphrase is returned when the secrets cannot be decrypted
+	// using the given passphrase.
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+)
+
+const (
+	version = 1
+	saltLen = 16
+	keyLen  = 32 // AES-256
+
+	// scrypt parameters as recommended for interactive logins.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+const fileSuffix = "_secret.enc"
+
+// envelope is the on-disk format of a single encrypted secret file. Byte
+// slices are encoded as base64 by encoding/json.
+type envelope struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+type Provider struct {
+	dir string
+
+	mutex sync.Mutex
+	pass  []byte
+	salt  []byte            // salt used for writing
+	keys  map[string][]byte // salt -> derived key
+}
+
+var _ driver.Provider = (*Provider)(nil)
+
+// NewProvider creates a new locked provider that stores its files inside the
+// given directory.
+func NewProvider(dir string) *Provider {
+	return &Provider{
+		dir:  dir,
+		keys: map[string][]byte{},
+	}
+}
+
+func (p *Provider) Name() string {
+	return "Encrypted File"
+}
+
+// HasSecrets returns true if there are any encrypted secret files.
+func (p *Provider) HasSecrets() bool {
+	return len(p.files()) > 0
+}
+
+// Locked returns true if the provider has not been unlocked yet.
+func (p *Provider) Locked() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.pass == nil
+}
+
+// Unlock unlocks the provider with the given passphrase. If there are existing
+// secret files, then the passphrase is verified against them, and
+// ErrWrongPassphrase is returned if it opens none of them. Files that can't be
+// read at all are skipped, so a single damaged file doesn't lock the user out.
+func (p *Provider) Unlock(passphrase string) error {
+	if passphrase == "" {
+		return errors.New("passphrase must not be empty")
+	}
+
+	var pass = []byte(passphrase)
+	var keys = map[string][]byte{}
+
+	salt, err := verifyPassphrase(pass, p.files(), keys)
+	if err != nil {
+		return err
+	}
+
+	if salt == nil {
+		salt = make([]byte, saltLen)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return errors.Wrap(err, "Failed to generate salt")
+		}
+
+		k, err := deriveKey(pass, salt)
+		if err != nil {
+			return err
+		}
+
+		keys[string(salt)] = k
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.pass = pass
+	p.salt = salt
+	p.keys = keys
+
+	return nil
+}
+
+// verifyPassphrase tries to open the files with the passphrase until one
+// opens, then returns that file's salt. The derived keys are stored in keys. A
+// nil salt is returned if no file could be read.
+func verifyPassphrase(pass []byte, files []string, keys map[string][]byte) ([]byte, error) {
+	var wrong bool
+
+	for _, file := range files {
+		env, err := readEnvelope(file)
+		if err != nil {
+			log.Info(errors.Wrap(err, "Skipping unreadable encrypted file"))
+			continue
+		}
+
+		key, ok := keys[string(env.Salt)]
+		if !ok {
+			key, err = deriveKey(pass, env.Salt)
+			if err != nil {
+				return nil, err
+			}
+			keys[string(env.Salt)] = key
+		}
+
+		if _, err := env.open(key, serviceFromFile(file)); err != nil {
+			wrong = true
+			continue
+		}
+
+		return env.Salt, nil
+	}
+
+	if wrong {
+		return nil, ErrWrongPassphrase
+	}
+
+	return nil, nil
+}
+
+// key returns the key for the given salt, deriving it if needed.
+func (p *Provider) key(salt []byte) ([]byte, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.pass == nil {
+		return nil, ErrLocked
+	}
+
+	if key, ok := p.keys[string(salt)]; ok {
+		return key, nil
+	}
+
+	key, err := deriveKey(p.pass, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	p.keys[string(salt)] = key
+	return key, nil
+}
+
+// Get decrypts the service's secret file and unmarshals it into v.
+func (p *Provider) Get(service string, v interface{}) error {
+	if p.Locked() {
+		return ErrLocked
+	}
+
+	env, err := readEnvelope(p.path(service))
+	if err != nil {
+		return err
+	}
+
+	key, err := p.key(env.Salt)
+	if err != nil {
+		return err
+	}
+
+	b, err := env.open(key, service)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		return errors.Wrap(err, "Failed to decode JSON")
+	}
+
+	return nil
+}
+
+// Set encrypts v and writes it into the service's secret file.
+func (p *Provider) Set(service string, v interface{}) error {
+	p.mutex.Lock()
+	salt := p.salt
+	p.mutex.Unlock()
+
+	key, err := p.key(salt)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode JSON")
+	}
+
+	env, err := seal(key, salt, service, b)
+	if err != nil {
+		return err
+	}
+
+	b, err = json.Marshal(env)
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode encrypted file")
+	}
+
+	return writeFile(p.path(service), b)
+}
+
+// writeFile writes the file into a temporary file first, then renames it over
+// the old one, so the old secrets are kept if writing fails halfway.
+func writeFile(path string, b []byte) error {
+	// TempFile creates the file with 0600.
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return errors.Wrap(err, "Failed to create temporary file")
+	}
+	// Clean up the temporary file on failure. This is a no-op after a
+	// successful rename.
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return errors.Wrap(err, "Failed to write encrypted file")
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return errors.Wrap(err, "Failed to sync encrypted file")
+	}
+
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "Failed to close encrypted file")
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return errors.Wrap(err, "Failed to replace encrypted file")
+	}
+
+	return nil
+}
+
+// Delete removes the service's secret file. The provider does not need to be
+// unlocked for this.
+func (p *Provider) Delete(service string) error {
+	err := os.Remove(p.path(service))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "Failed to remove file")
+	}
+	return nil
+}
+
+// Contains returns true if the service's secret file exists. The provider does
+// not need to be unlocked for this.
+func (p *Provider) Contains(service string) (bool, error) {
+	_, err := os.Stat(p.path(service))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "Failed to stat file")
+	}
+	return true, nil
+}
+
+// SealWithPassphrase encrypts the given plaintext into a self-contained blob
+// using a key derived from the passphrase and a new random salt. The name is
+// authenticated along with the data and must be given again to open the blob.
+func SealWithPassphrase(passphrase, name string, plain []byte) ([]byte, error) {
+	salt := make([]byte, saltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, errors.Wrap(err, "Failed to generate salt")
+	}
+
+	key, err := deriveKey([]byte(passphrase), salt)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := seal(key, salt, name, plain)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(env)
+}
+
+// OpenWithPassphrase decrypts a blob created by SealWithPassphrase.
+func OpenWithPassphrase(passphrase, name string, blob []byte) ([]byte, error) {
+	var env envelope
+	if err := json.Unmarshal(blob, &env); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode encrypted blob")
+	}
+
+	if env.Version != version {
+		return nil, fmt.Errorf("unknown encrypted blob version %d", env.Version)
+	}
+
+	key, err := deriveKey([]byte(passphrase), env.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return env.open(key, name)
+}
+
+func (p *Provider) path(service string) string {
+	return filepath.Join(p.dir, jsondriver.SanitizeName(service)+fileSuffix)
+}
+
+func (p *Provider) files() []string {
+	files, _ := filepath.Glob(filepath.Join(p.dir, "*"+fileSuffix))
+	return files
+}
+
+// serviceFromFile returns the sanitized service name from the secret file's
+// path. This is enough to open the file, since sealing also uses the sanitized
+// name.
+func serviceFromFile(path string) string {
+	name := filepath.Base(path)
+	return name[:len(name)-len(fileSuffix)]
+}
+
+func readEnvelope(path string) (*envelope, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open file")
+	}
+	defer f.Close()
+
+	if err := checkPermission(f); err != nil {
+		return nil, err
+	}
+
+	var env envelope
+	if err := json.NewDecoder(f).Decode(&env); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode encrypted file")
+	}
+
+	if env.Version != version {
+		return nil, fmt.Errorf("unknown encrypted file version %d", env.Version)
+	}
+
+	return &env, nil
+}
+
+func checkPermission(f *os.File) error {
+	s, err := f.Stat()
+	if err != nil {
+		return errors.Wrap(err, "Failed to stat file")
+	}
+	if m := s.Mode(); m != 0600 {
+		return fmt.Errorf("%s file has unsafe permission %04o", filepath.Base(f.Name()), m)
+	}
+	return nil
+}
+
+func deriveKey(pass, salt []byte) ([]byte, error) {
+	key, err := scrypt.Key(pass, salt, scryptN, scryptR, scryptP, keyLen)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to derive key")
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the given plaintext. The sanitized service name is used as
+// the additional data, so files cannot be swapped around.
+func seal(key, salt []byte, service string, plain []byte) (*envelope, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create cipher")
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, "Failed to generate nonce")
+	}
+
+	return &envelope{
+		Version: version,
+		Salt:    salt,
+		Nonce:   nonce,
+		Data:    gcm.Seal(nil, nonce, plain, []byte(jsondriver.SanitizeName(service))),
+	}, nil
+}
+
+func (env *envelope) open(key []byte, service string) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create cipher")
+	}
+
+	if len(env.Nonce) != gcm.NonceSize() {
+		return nil, errors.New("invalid nonce size")
+	}
+
+	b, err := gcm.Open(nil, env.Nonce, env.Data, []byte(jsondriver.SanitizeName(service)))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	return b, nil
+}
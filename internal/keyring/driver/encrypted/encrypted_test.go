@@ -0,0 +1,115 @@
+package encrypted
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type secret struct {
+	Token string
+	IDs   []int
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "cchat-encrypted-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return dir
+}
+
+func TestRoundTrip(t *testing.T) {
+	dir := tempDir(t)
+	want := secret{Token: "hunter2", IDs: []int{1, 2}}
+
+	p := NewProvider(dir)
+	if err := p.Set("service", want); err != ErrLocked {
+		t.Fatalf("expected ErrLocked before unlocking, got %v", err)
+	}
+
+	if err := p.Unlock("passphrase"); err != nil {
+		t.Fatal("failed to unlock:", err)
+	}
+	if err := p.Set("service", want); err != nil {
+		t.Fatal("failed to set:", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 {
+		t.Fatalf("expected only the secret file, got %v", files)
+	}
+
+	// Unlock again from the files alone.
+	p = NewProvider(dir)
+	if err := p.Unlock("passphrase"); err != nil {
+		t.Fatal("failed to unlock with existing files:", err)
+	}
+
+	var got secret
+	if err := p.Get("service", &got); err != nil {
+		t.Fatal("failed to get:", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected secret %#v", got)
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	dir := tempDir(t)
+
+	p := NewProvider(dir)
+	if err := p.Unlock("passphrase"); err != nil {
+		t.Fatal("failed to unlock:", err)
+	}
+	if err := p.Set("service", secret{Token: "hunter2"}); err != nil {
+		t.Fatal("failed to set:", err)
+	}
+
+	p = NewProvider(dir)
+	if err := p.Unlock("wrong"); err != ErrWrongPassphrase {
+		t.Fatalf("expected ErrWrongPassphrase, got %v", err)
+	}
+	if !p.Locked() {
+		t.Fatal("provider unlocked with the wrong passphrase")
+	}
+}
+
+func TestUnlockDamaged(t *testing.T) {
+	dir := tempDir(t)
+
+	p := NewProvider(dir)
+	if err := p.Unlock("passphrase"); err != nil {
+		t.Fatal("failed to unlock:", err)
+	}
+	if err := p.Set("service", secret{Token: "hunter2"}); err != nil {
+		t.Fatal("failed to set:", err)
+	}
+
+	// Sorts before the intact file.
+	damaged := filepath.Join(dir, "a"+fileSuffix)
+	if err := ioutil.WriteFile(damaged, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p = NewProvider(dir)
+	if err := p.Unlock("passphrase"); err != nil {
+		t.Fatal("failed to unlock with a damaged file:", err)
+	}
+
+	var got secret
+	if err := p.Get("service", &got); err != nil || got.Token != "hunter2" {
+		t.Fatalf("unexpected secret %#v, error %v", got, err)
+	}
+
+	p = NewProvider(dir)
+	if err := p.Unlock("wrong"); err != ErrWrongPassphrase {
+		t.Fatalf("expected ErrWrongPassphrase, got %v", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"unicode"
 
+	"github.com/diamondburned/cchat-gtk/internal/keyring/driver"
 	"github.com/pkg/errors"
 )
 
@@ -17,10 +18,20 @@ type Provider struct {
 	dir string
 }
 
+var _ driver.Provider = (*Provider)(nil)
+
 func NewProvider(dir string) Provider {
 	return Provider{dir}
 }
 
+func (p Provider) Name() string {
+	return "Plaintext File"
+}
+
+func (p Provider) filename(service string) string {
+	return fmt.Sprintf("%s_secret.json", SanitizeName(service))
+}
+
 func (p Provider) open(service string, write bool) (*os.File, error) {
 	var flags int
 	if write {
@@ -34,7 +45,7 @@ func (p Provider) open(service string, write bool) (*os.File, error) {
 	}
 
 	// Make a filename using the given service.
-	var filename = fmt.Sprintf("%s_secret.json", SanitizeName(service))
+	var filename = p.filename(service)
 
 	f, err := os.OpenFile(filepath.Join(p.dir, filename), flags, 0600)
 	if err != nil {
@@ -89,6 +100,27 @@ func (p Provider) Set(service string, v interface{}) error {
 	return nil
 }
 
+// Delete removes the service's JSON secret file.
+func (p Provider) Delete(service string) error {
+	err := os.Remove(filepath.Join(p.dir, p.filename(service)))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "Failed to remove file")
+	}
+	return nil
+}
+
+// Contains returns true if the service's JSON secret file exists.
+func (p Provider) Contains(service string) (bool, error) {
+	_, err := os.Stat(filepath.Join(p.dir, p.filename(service)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "Failed to stat file")
+	}
+	return true, nil
+}
+
 // SanitizeName sanitizes the name so that it's safe to use as a filename.
 func SanitizeName(name string) string {
 	// Escape all weird characters in a filename.
@@ -6,9 +6,13 @@ import (
 	"strings"
 
 	"github.com/diamondburned/cchat-gtk/internal/keyring/driver"
+	"github.com/diamondburned/cchat-gtk/internal/profile"
 	"github.com/zalando/go-keyring"
 )
 
+// serviceName is the keyring service name, which is namespaced by the profile.
+var serviceName = profile.Namespace("cchat-gtk")
+
 type Provider struct{}
 
 var _ driver.Provider = (*Provider)(nil)
@@ -17,8 +21,19 @@ func NewProvider() driver.Provider {
 	return Provider{}
 }
 
+// Available returns true if the system keyring can be reached. A missing
+// secret is not an error.
+func Available() bool {
+	_, err := keyring.Get(serviceName, "")
+	return err == nil || err == keyring.ErrNotFound
+}
+
+func (Provider) Name() string {
+	return "System Keyring"
+}
+
 func (Provider) Get(service string, v interface{}) error {
-	s, err := keyring.Get("cchat-gtk", service)
+	s, err := keyring.Get(serviceName, service)
 	if err != nil {
 		return err
 	}
@@ -32,5 +47,24 @@ func (Provider) Set(service string, v interface{}) error {
 		return err
 	}
 
-	return keyring.Set("cchat-gtk", service, b.String())
+	return keyring.Set(serviceName, service, b.String())
+}
+
+func (Provider) Delete(service string) error {
+	if err := keyring.Delete(serviceName, service); err != keyring.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+func (Provider) Contains(service string) (bool, error) {
+	_, err := keyring.Get(serviceName, service)
+	switch err {
+	case nil:
+		return true, nil
+	case keyring.ErrNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
 }
@@ -3,6 +3,7 @@ package keyring
 import (
 	"github.com/diamondburned/cchat"
 	"github.com/diamondburned/cchat-gtk/internal/keyring/driver"
+	"github.com/diamondburned/cchat-gtk/internal/keyring/driver/encrypted"
 	"github.com/diamondburned/cchat-gtk/internal/keyring/driver/json"
 	"github.com/diamondburned/cchat-gtk/internal/keyring/driver/keyring"
 	"github.com/diamondburned/cchat-gtk/internal/log"
@@ -10,12 +11,39 @@ import (
 	"github.com/pkg/errors"
 )
 
+// encryptedProvider stores passphrase-encrypted secrets. It stays locked and
+// is skipped by the store until Unlock is called.
+var encryptedProvider = encrypted.NewProvider(config.DirPath())
+
 // Declare a keyring store with fallbacks.
 var store = driver.NewStore(
 	keyring.NewProvider(),
+	encryptedProvider,
 	json.NewProvider(config.DirPath()), // fallback
 )
 
+// NeedsPassphrase returns true if the user should be prompted for a passphrase
+// to unlock the encrypted provider. This is the case when the system keyring
+// is unavailable or when there are already encrypted secrets.
+func NeedsPassphrase() bool {
+	if !encryptedProvider.Locked() {
+		return false
+	}
+
+	return encryptedProvider.HasSecrets() || !keyring.Available()
+}
+
+// HasEncryptedSecrets returns true if there are existing encrypted secrets. If
+// false, then Unlock will set a new passphrase.
+func HasEncryptedSecrets() bool {
+	return encryptedProvider.HasSecrets()
+}
+
+// Unlock unlocks the encrypted provider using the given passphrase.
+func Unlock(passphrase string) error {
+	return encryptedProvider.Unlock(passphrase)
+}
+
 type Session struct {
 	ID cchat.ID
 
@@ -102,3 +130,22 @@ func (svc Service) Save() {
 		log.Warn(errors.Wrap(err, "Error saving session"))
 	}
 }
+
+// Providers returns all keyring providers in the order that they are tried.
+func Providers() []driver.Provider {
+	return store.Providers()
+}
+
+// Locate returns the providers that hold the sessions of the service with the
+// given ID. The first provider is the one that sessions are restored from.
+func Locate(serviceID cchat.ID) []driver.Provider {
+	return store.Locate(serviceID)
+}
+
+// Migrate moves the sessions of the service with the given ID from one
+// provider to another, deleting the stale copy afterwards. driver.ErrExists is
+// returned if the destination already has the service's sessions.
+func Migrate(serviceID cchat.ID, from, to driver.Provider) error {
+	var sessions []Session
+	return store.Migrate(serviceID, from, to, &sessions)
+}
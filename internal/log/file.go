@@ -0,0 +1,127 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// FileName is the name of the log file in the log directory.
+	FileName = "cchat-gtk.log"
+	// MaxFileSize is the size at which the log file is rotated.
+	MaxFileSize = 1 << 20 // 1MB
+	// MaxFiles is the number of rotated files kept besides the current one.
+	MaxFiles = 3
+)
+
+var logFile struct {
+	sync.Mutex
+	path   string
+	file   *os.File
+	size   int64
+	remove func()
+}
+
+// OpenFile starts writing all new entries into a log file in the given
+// directory. The file is rotated once it grows past MaxFileSize, keeping up to
+// MaxFiles old files suffixed with a number, such as cchat-gtk.log.1.
+func OpenFile(dir string) error {
+	CloseFile()
+
+	if err := os.MkdirAll(dir, 0755|os.ModeDir); err != nil {
+		return errors.Wrap(err, "failed to make log dir")
+	}
+
+	logFile.Lock()
+	defer logFile.Unlock()
+
+	logFile.path = filepath.Join(dir, FileName)
+
+	if err := openLogFile(); err != nil {
+		return err
+	}
+
+	logFile.remove = AddEntryHandler(writeFileEntry)
+	return nil
+}
+
+// CloseFile stops writing into the log file. It does nothing if no log file is
+// opened.
+func CloseFile() {
+	logFile.Lock()
+	defer logFile.Unlock()
+
+	if logFile.remove != nil {
+		logFile.remove()
+		logFile.remove = nil
+	}
+
+	if logFile.file != nil {
+		logFile.file.Close()
+		logFile.file = nil
+	}
+}
+
+func openLogFile() error {
+	f, err := os.OpenFile(logFile.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to open log file")
+	}
+
+	s, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return errors.Wrap(err, "failed to stat log file")
+	}
+
+	logFile.file = f
+	logFile.size = s.Size()
+	return nil
+}
+
+func writeFileEntry(entry Entry) {
+	logFile.Lock()
+	defer logFile.Unlock()
+
+	if logFile.file == nil {
+		return
+	}
+
+	n, err := fmt.Fprintln(logFile.file, entry)
+	logFile.size += int64(n)
+
+	if err != nil || logFile.size < MaxFileSize {
+		return
+	}
+
+	if err := rotate(); err != nil {
+		// Don't log this error, since that would recurse into this handler.
+		fmt.Fprintln(os.Stderr, "Failed to rotate log file:", err)
+	}
+}
+
+// rotate shifts the old files up by one, dropping the oldest, then starts a new
+// file.
+func rotate() error {
+	logFile.file.Close()
+	logFile.file = nil
+
+	for i := MaxFiles - 1; i > 0; i-- {
+		os.Rename(rotated(i), rotated(i+1))
+	}
+
+	if err := os.Rename(logFile.path, rotated(1)); err != nil {
+		return err
+	}
+
+	return openLogFile()
+}
+
+func rotated(n int) string {
+	return logFile.path + "." + strconv.Itoa(n)
+}
@@ -10,10 +10,16 @@ import (
 	"time"
 )
 
+// BufferSize is the maximum number of entries kept in memory. Older entries are
+// dropped once the buffer is full.
+const BufferSize = 2000
+
 var globalBuffer struct {
 	sync.Mutex
-	entries  []Entry
-	handlers []func(Entry)
+	entries  []Entry // ring buffer
+	start    int     // index of the oldest entry
+	handlers map[int]func(Entry)
+	serial   int
 }
 
 func init() {
@@ -22,18 +28,90 @@ func init() {
 	})
 }
 
+// Level is the severity of an entry.
+type Level uint8
+
+const (
+	LevelInfo Level = iota
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelInfo:
+		return "Info"
+	case LevelWarn:
+		return "Warn"
+	case LevelError:
+		return "Error"
+	default:
+		return "???"
+	}
+}
+
 type Entry struct {
-	Time time.Time
-	Msg  string
+	Time  time.Time
+	Level Level
+	Msg   string
 }
 
 func (entry Entry) String() string {
-	return entry.Time.Format(time.Stamp) + ": " + entry.Msg
+	return entry.Time.Format(time.Stamp) + ": " + entry.Level.String() + ": " + entry.Msg
+}
+
+// AddEntryHandler adds a handler, which will run asynchronously. The returned
+// callback removes the handler.
+func AddEntryHandler(fn func(Entry)) (remove func()) {
+	globalBuffer.Lock()
+	defer globalBuffer.Unlock()
+
+	return addEntryHandler(fn)
+}
+
+// SubscribeEntries returns a copy of all buffered entries like Entries and adds
+// the handler like AddEntryHandler at once, so that every entry is either in
+// the returned entries or given to the handler.
+func SubscribeEntries(fn func(Entry)) (entries []Entry, remove func()) {
+	globalBuffer.Lock()
+	defer globalBuffer.Unlock()
+
+	return bufferedEntries(), addEntryHandler(fn)
+}
+
+// addEntryHandler adds the handler. The mutex must be acquired.
+func addEntryHandler(fn func(Entry)) (remove func()) {
+	if globalBuffer.handlers == nil {
+		globalBuffer.handlers = map[int]func(Entry){}
+	}
+
+	id := globalBuffer.serial
+	globalBuffer.serial++
+	globalBuffer.handlers[id] = fn
+
+	return func() {
+		globalBuffer.Lock()
+		delete(globalBuffer.handlers, id)
+		globalBuffer.Unlock()
+	}
 }
 
-// AddEntryHandler adds a handler, which will run asynchronously.
-func AddEntryHandler(fn func(Entry)) {
-	globalBuffer.handlers = append(globalBuffer.handlers, fn)
+// Entries returns a copy of all buffered entries from oldest to newest.
+func Entries() []Entry {
+	globalBuffer.Lock()
+	defer globalBuffer.Unlock()
+
+	return bufferedEntries()
+}
+
+// bufferedEntries returns a copy of all buffered entries. The mutex must be
+// acquired.
+func bufferedEntries() []Entry {
+	var entries = make([]Entry, 0, len(globalBuffer.entries))
+	entries = append(entries, globalBuffer.entries[globalBuffer.start:]...)
+	entries = append(entries, globalBuffer.entries[:globalBuffer.start]...)
+
+	return entries
 }
 
 func Error(err error) {
@@ -47,32 +125,52 @@ func Error(err error) {
 		return
 	}
 
-	Write("Error: " + err.Error())
+	WriteLevel(LevelError, err.Error())
 }
 
-// Warn calls Info().
+// Warn logs the error as a warning, which is something that went wrong but is
+// recoverable.
 func Warn(err error) {
-	Info(err)
+	WriteLevel(LevelWarn, err.Error())
 }
 
 func Info(err error) {
-	Write("Info: " + err.Error())
+	WriteLevel(LevelInfo, err.Error())
 }
 
+// Write writes the message as an Info entry.
 func Write(msg string) {
+	WriteLevel(LevelInfo, msg)
+}
+
+func WriteLevel(level Level, msg string) {
 	WriteEntry(Entry{
-		Time: time.Now(),
-		Msg:  msg,
+		Time:  time.Now(),
+		Level: level,
+		Msg:   msg,
 	})
 }
 
 func WriteEntry(entry Entry) {
 	go func() {
 		globalBuffer.Lock()
-		globalBuffer.entries = append(globalBuffer.entries, entry)
-		globalBuffer.Unlock()
 
+		if len(globalBuffer.entries) < BufferSize {
+			globalBuffer.entries = append(globalBuffer.entries, entry)
+		} else {
+			// Overwrite the oldest entry.
+			globalBuffer.entries[globalBuffer.start] = entry
+			globalBuffer.start = (globalBuffer.start + 1) % BufferSize
+		}
+
+		var handlers = make([]func(Entry), 0, len(globalBuffer.handlers))
 		for _, fn := range globalBuffer.handlers {
+			handlers = append(handlers, fn)
+		}
+
+		globalBuffer.Unlock()
+
+		for _, fn := range handlers {
 			fn(entry)
 		}
 	}()
@@ -0,0 +1,43 @@
+package offline
+
+// #cgo pkg-config: gio-2.0
+// #include <gio/gio.h>
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/pkg/errors"
+)
+
+// monitor is the default GNetworkMonitor. It is kept so that its signal
+// handler stays alive.
+var monitor *glib.Object
+
+// Monitor starts following the network state from GNetworkMonitor. It must be
+// called in the main thread after GTK is initialized.
+func Monitor() {
+	if monitor != nil {
+		return
+	}
+
+	monitor = glib.Take(unsafe.Pointer(C.g_network_monitor_get_default()))
+	monitor.Connect("network-changed", updateNetwork)
+
+	updateNetwork()
+}
+
+func updateNetwork() {
+	v, err := monitor.GetProperty("network-available")
+	if err != nil {
+		log.Error(errors.Wrap(err, "Failed to get the network state"))
+		return
+	}
+
+	available, _ := v.(bool)
+	noNetwork = !available
+
+	update()
+}
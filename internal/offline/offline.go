@@ -0,0 +1,66 @@
+// Package offline keeps track of whether cchat-gtk is offline, either because
+// the network is unavailable or because the user chose to work offline.
+package offline
+
+import (
+	"sync/atomic"
+
+	"github.com/diamondburned/cchat-gtk/internal/ui/config"
+)
+
+var (
+	// manual is true if the user chose to work offline.
+	manual bool
+	// noNetwork is true if the network monitor reports no network.
+	noNetwork bool
+
+	isOffline uint32 // atomic
+)
+
+var handlers = struct {
+	m      map[int]func(offline bool)
+	serial int
+}{
+	m: map[int]func(bool){},
+}
+
+func init() {
+	config.Register(config.Network, "Work Offline", config.Describe(
+		"Only show cached media and queue outgoing messages until this is "+
+			"turned off.",
+		config.Switch(&manual, func(bool) { update() }),
+	))
+}
+
+// IsOffline returns true if cchat-gtk is offline. It is thread-safe.
+func IsOffline() bool {
+	return atomic.LoadUint32(&isOffline) == 1
+}
+
+// OnChange adds a callback that is called in the main thread when cchat-gtk
+// goes offline or back online. The returned callback removes it. This function
+// is not thread-safe.
+func OnChange(f func(offline bool)) (remove func()) {
+	id := handlers.serial
+	handlers.serial++
+	handlers.m[id] = f
+
+	return func() { delete(handlers.m, id) }
+}
+
+// update recalculates the state and calls the handlers if it changed. It must
+// be called in the main thread.
+func update() {
+	var offline uint32
+	if manual || noNetwork {
+		offline = 1
+	}
+
+	if atomic.SwapUint32(&isOffline, offline) == offline {
+		return
+	}
+
+	for _, f := range handlers.m {
+		f(offline == 1)
+	}
+}
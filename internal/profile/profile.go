@@ -0,0 +1,175 @@
+// Package profile parses the profile command-line flags. It is initialized
+// before everything else, since the config directory, keyring and application
+// ID all depend on the active profile.
+//
+// The following flags are consumed and removed from the arguments given to
+// Gtk:
+//
+//	--profile <name>      use the named profile, overrides $CCHAT_GTK_PROFILE
+//	--config-dir <path>   use the given config directory as-is
+//
+// A config directory given without a profile still gets its own keyring
+// entries, cache and application ID, which are derived from its path.
+package profile
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// EnvName is the environment variable that selects the profile if the
+// --profile flag is not given.
+const EnvName = "CCHAT_GTK_PROFILE"
+
+var (
+	name      string
+	configDir string
+	namespace string
+	args      []string
+)
+
+func init() {
+	var opts = options{name: os.Getenv(EnvName)}
+	var err error
+
+	args, err = parse(os.Args, &opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cchat-gtk:", err)
+		os.Exit(2)
+	}
+
+	name = sanitize(opts.name)
+	configDir = opts.configDir
+	namespace = deriveNamespace(name, configDir)
+}
+
+// options are the values of the profile flags.
+type options struct {
+	name      string
+	configDir string
+}
+
+// parse consumes the profile flags from the given arguments into opts and
+// returns the rest. An error is returned if a flag has no value.
+func parse(argv []string, opts *options) ([]string, error) {
+	var rest = make([]string, 0, len(argv))
+
+	for i := 0; i < len(argv); i++ {
+		arg := argv[i]
+
+		// Stop parsing after the double-dash separator.
+		if arg == "--" {
+			rest = append(rest, argv[i:]...)
+			break
+		}
+
+		var flag string
+		var dst *string
+
+		switch {
+		case arg == "--profile", strings.HasPrefix(arg, "--profile="):
+			flag, dst = "--profile", &opts.name
+		case arg == "--config-dir", strings.HasPrefix(arg, "--config-dir="):
+			flag, dst = "--config-dir", &opts.configDir
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+
+		var value string
+
+		if eq := strings.IndexByte(arg, '='); eq > -1 {
+			value = arg[eq+1:]
+		} else if i+1 < len(argv) && !strings.HasPrefix(argv[i+1], "-") {
+			i++
+			value = argv[i]
+		}
+
+		if value == "" {
+			return nil, fmt.Errorf("flag %s needs a value", flag)
+		}
+
+		*dst = value
+	}
+
+	return rest, nil
+}
+
+// deriveNamespace returns the name that resources of the profile are suffixed
+// with. A config directory given without a profile gets its own namespace, so
+// that it doesn't share the sessions and cache of the default profile.
+func deriveNamespace(name, configDir string) string {
+	if name != "" || configDir == "" {
+		return name
+	}
+
+	if abs, err := filepath.Abs(configDir); err == nil {
+		configDir = abs
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(filepath.Clean(configDir)))
+
+	return fmt.Sprintf("dir-%08x", h.Sum32())
+}
+
+// sanitize makes the profile name safe to use in paths and the application ID.
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsNumber(r) || r == '-') {
+			return unicode.ToLower(r)
+		}
+		return '_'
+	}, name)
+}
+
+// Args returns the command-line arguments without the profile flags.
+func Args() []string {
+	return append([]string{}, args...)
+}
+
+// Name returns the name of the active profile. An empty string is returned for
+// the default profile.
+func Name() string {
+	return name
+}
+
+// ConfigDir returns the config directory given by --config-dir. An empty
+// string is returned if the flag is not given.
+func ConfigDir() string {
+	return configDir
+}
+
+// CacheDir returns the cache directory of the active profile, which holds the
+// media cache, log files and crash reports. It is not created.
+func CacheDir() string {
+	d, err := os.UserCacheDir()
+	if err != nil {
+		d = os.TempDir()
+	}
+	return filepath.Join(d, Namespace("cchat-gtk"))
+}
+
+// Namespace suffixes the given name with the active profile's name, so that
+// resources of different profiles do not collide. Profiles given only by their
+// config directory are named after a hash of its path. The name is returned
+// as-is for the default profile.
+func Namespace(base string) string {
+	if namespace == "" {
+		return base
+	}
+	return base + "-" + namespace
+}
+
+// AppID returns the application ID for the active profile, which allows
+// multiple profiles to run concurrently.
+func AppID(base string) string {
+	if namespace == "" {
+		return base
+	}
+	return base + ".profile-" + namespace
+}
@@ -0,0 +1,87 @@
+package profile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	var tests = []struct {
+		name string
+		argv []string
+		opts options
+		rest []string
+		err  bool
+	}{{
+		name: "none",
+		argv: []string{"cchat-gtk", "--gapplication-service"},
+		rest: []string{"cchat-gtk", "--gapplication-service"},
+	}, {
+		name: "separate values",
+		argv: []string{"cchat-gtk", "--profile", "work", "--config-dir", "/tmp/a"},
+		opts: options{name: "work", configDir: "/tmp/a"},
+		rest: []string{"cchat-gtk"},
+	}, {
+		name: "equal values",
+		argv: []string{"cchat-gtk", "--profile=work", "-v"},
+		opts: options{name: "work"},
+		rest: []string{"cchat-gtk", "-v"},
+	}, {
+		name: "after separator",
+		argv: []string{"cchat-gtk", "--", "--profile", "work"},
+		rest: []string{"cchat-gtk", "--", "--profile", "work"},
+	}, {
+		name: "trailing",
+		argv: []string{"cchat-gtk", "--config-dir"},
+		err:  true,
+	}, {
+		name: "followed by flag",
+		argv: []string{"cchat-gtk", "--profile", "--config-dir", "/tmp/a"},
+		err:  true,
+	}, {
+		name: "empty",
+		argv: []string{"cchat-gtk", "--profile="},
+		err:  true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var opts options
+
+			rest, err := parse(test.argv, &opts)
+			if test.err {
+				if err == nil {
+					t.Fatal("Expected an error, got rest", rest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+
+			if opts != test.opts {
+				t.Errorf("Unexpected options %+v", opts)
+			}
+			if !reflect.DeepEqual(rest, test.rest) {
+				t.Errorf("Unexpected rest %q", rest)
+			}
+		})
+	}
+}
+
+func TestDeriveNamespace(t *testing.T) {
+	if ns := deriveNamespace("work", "/tmp/a"); ns != "work" {
+		t.Errorf("Profile name not used: %q", ns)
+	}
+	if ns := deriveNamespace("", ""); ns != "" {
+		t.Errorf("Default profile has a namespace: %q", ns)
+	}
+
+	a := deriveNamespace("", "/tmp/a")
+	if a == "" || a != deriveNamespace("", "/tmp/a/") {
+		t.Errorf("Unstable namespace for the config dir: %q", a)
+	}
+	if a == deriveNamespace("", "/tmp/b") {
+		t.Errorf("Different config dirs share the namespace %q", a)
+	}
+}
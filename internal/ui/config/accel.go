@@ -0,0 +1,105 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gotk3/gotk3/gdk"
+	"github.com/gotk3/gotk3/gtk"
+)
+
+type _accel struct {
+	value  *string
+	change func(string)
+}
+
+// Accelerator creates a button that records a keyboard shortcut when clicked.
+// The shortcut is stored in the format of gtk.AcceleratorName, such as
+// "<Primary>k". An empty string means no shortcut.
+func Accelerator(value *string, change func(string)) EntryValue {
+	return &_accel{value, change}
+}
+
+func (a *_accel) set(v string) error {
+	if v != "" {
+		if key, mods := gtk.AcceleratorParse(v); !gtk.AcceleratorValid(key, mods) {
+			return fmt.Errorf("invalid shortcut %q", v)
+		}
+	}
+
+	*a.value = v
+	if a.change != nil {
+		a.change(v)
+	}
+	return nil
+}
+
+func accelLabel(v string) string {
+	if v == "" {
+		return "Disabled"
+	}
+	return gtk.AcceleratorGetLabel(gtk.AcceleratorParse(v))
+}
+
+func (a *_accel) Construct() gtk.IWidget {
+	button, _ := gtk.ToggleButtonNewWithLabel(accelLabel(*a.value))
+	button.SetTooltipText("Click, then press the new shortcut. Backspace disables it.")
+	button.Show()
+
+	fb := newFeedback(button)
+
+	button.Connect("toggled", func(button *gtk.ToggleButton) {
+		if button.GetActive() {
+			button.SetLabel("Press a shortcut...")
+		} else {
+			button.SetLabel(accelLabel(*a.value))
+		}
+	})
+
+	button.Connect("key-press-event", func(button *gtk.ToggleButton, ev *gdk.Event) bool {
+		if !button.GetActive() {
+			return false
+		}
+
+		var key, mask = convEvent(ev)
+		var mods = gdk.ModifierType(mask) & gtk.AcceleratorGetDefaultModMask()
+
+		switch {
+		case key == gdk.KEY_Escape && mods == 0:
+			fb.set(nil)
+		case key == gdk.KEY_BackSpace && mods == 0:
+			fb.set(a.set(""))
+		case !gtk.AcceleratorValid(key, mods):
+			// Most likely a lone modifier key; wait for the rest.
+			return true
+		default:
+			fb.set(a.set(gtk.AcceleratorName(gdk.KeyvalToLower(key), mods)))
+		}
+
+		button.SetActive(false)
+		return true
+	})
+
+	button.Connect("focus-out-event", func(button *gtk.ToggleButton) {
+		button.SetActive(false)
+	})
+
+	return fb
+}
+
+func convEvent(ev *gdk.Event) (key, mask uint) {
+	var keyEvent = gdk.EventKeyNewFromEvent(ev)
+	return keyEvent.KeyVal(), keyEvent.State()
+}
+
+func (a *_accel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(*a.value)
+}
+
+func (a *_accel) UnmarshalJSON(b []byte) error {
+	var value string
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	return a.set(value)
+}
@@ -0,0 +1,403 @@
+// Package bundle provides exporting and importing the whole user profile,
+// which includes all config files and optionally the saved sessions, as a
+// single zip archive.
+package bundle
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/cchat-gtk/internal/keyring"
+	"github.com/diamondburned/cchat-gtk/internal/keyring/driver/encrypted"
+	"github.com/diamondburned/cchat-gtk/internal/ui/config"
+	"github.com/diamondburned/cchat/services"
+	"github.com/pkg/errors"
+)
+
+// Version is the current bundle format version.
+const Version = 1
+
+// FileExt is the preferred file extension of profile bundles.
+const FileExt = ".cchat-profile"
+
+const (
+	manifestFile = "manifest.json"
+	sessionsFile = "sessions.enc"
+	configPrefix = "config/"
+)
+
+// sessionsName is the name authenticated with the encrypted sessions.
+const sessionsName = "sessions"
+
+// Manifest describes the content of a bundle.
+type Manifest struct {
+	Version     int       `json:"version"`
+	Created     time.Time `json:"created"`
+	HasSessions bool      `json:"has_sessions"`
+}
+
+// ExportOptions contains options for Export.
+type ExportOptions struct {
+	// Passphrase, if not empty, includes all saved sessions encrypted with the
+	// given passphrase. Sessions are never exported in plaintext.
+	Passphrase string
+}
+
+// Export writes all config files and optionally the saved sessions into the
+// given writer as a zip archive. Secret files in the config directory are
+// never exported as-is, and secret entries such as passwords are removed from
+// the main config.
+func Export(w io.Writer, opts ExportOptions) error {
+	z := zip.NewWriter(w)
+
+	manifest := Manifest{
+		Version:     Version,
+		Created:     time.Now(),
+		HasSessions: opts.Passphrase != "",
+	}
+
+	if err := writeJSON(z, manifestFile, manifest); err != nil {
+		return err
+	}
+
+	dir := config.DirPath()
+
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || isSecret(file) || isBackup(file) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+
+		name := configPrefix + filepath.ToSlash(rel)
+
+		// The main config has secret entries such as the proxy password.
+		if rel == config.ConfigFile {
+			return copyInWithoutSecrets(z, name, file)
+		}
+
+		return copyIn(z, name, file)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to export config files")
+	}
+
+	if manifest.HasSessions {
+		if err := exportSessions(z, opts.Passphrase); err != nil {
+			return errors.Wrap(err, "failed to export sessions")
+		}
+	}
+
+	return z.Close()
+}
+
+func exportSessions(z *zip.Writer, passphrase string) error {
+	svcs, _ := services.Get()
+
+	var sessions = make(map[string][]keyring.Session, len(svcs))
+	for _, svc := range svcs {
+		if service := keyring.Restore(svc); len(service.Sessions) > 0 {
+			sessions[service.ID] = service.Sessions
+		}
+	}
+
+	b, err := json.Marshal(sessions)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal sessions")
+	}
+
+	blob, err := encrypted.SealWithPassphrase(passphrase, sessionsName, b)
+	if err != nil {
+		return err
+	}
+
+	f, err := z.Create(sessionsFile)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(blob)
+	return err
+}
+
+// Conflict describes how to handle files and sessions that already exist when
+// importing.
+type Conflict uint8
+
+const (
+	// KeepExisting skips imported files and sessions that already exist.
+	KeepExisting Conflict = iota
+	// Overwrite replaces existing files and sessions with the imported ones.
+	Overwrite
+)
+
+// ImportOptions contains options for Import.
+type ImportOptions struct {
+	Conflict Conflict
+	// Passphrase is used to decrypt the sessions. If empty, then sessions are
+	// not imported.
+	Passphrase string
+}
+
+// ImportResult summarizes what Import did.
+type ImportResult struct {
+	Written  []string
+	Skipped  []string
+	Sessions int
+}
+
+// Reader is an opened bundle.
+type Reader struct {
+	z        *zip.Reader
+	Manifest Manifest
+}
+
+// Open opens a bundle and reads its manifest.
+func Open(r io.ReaderAt, size int64) (*Reader, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read bundle")
+	}
+
+	var reader = Reader{z: z}
+
+	if err := reader.readJSON(manifestFile, &reader.Manifest); err != nil {
+		return nil, errors.Wrap(err, "failed to read manifest")
+	}
+
+	if reader.Manifest.Version != Version {
+		return nil, fmt.Errorf("unsupported bundle version %d", reader.Manifest.Version)
+	}
+
+	return &reader, nil
+}
+
+// Import restores the bundle into the config directory and keyring. Restored
+// config files are only applied after a restart.
+func (r *Reader) Import(opts ImportOptions) (*ImportResult, error) {
+	var result ImportResult
+	var dir = config.DirPath()
+
+	for _, f := range r.z.File {
+		if !strings.HasPrefix(f.Name, configPrefix) || f.FileInfo().IsDir() {
+			continue
+		}
+
+		rel, err := cleanPath(strings.TrimPrefix(f.Name, configPrefix))
+		if err != nil {
+			return &result, err
+		}
+
+		if isSecret(rel) {
+			continue
+		}
+
+		dst := filepath.Join(dir, rel)
+
+		if _, err := os.Stat(dst); err == nil && opts.Conflict == KeepExisting {
+			result.Skipped = append(result.Skipped, rel)
+			continue
+		}
+
+		if err := copyOut(f, rel); err != nil {
+			return &result, errors.Wrapf(err, "failed to import %s", rel)
+		}
+
+		result.Written = append(result.Written, rel)
+	}
+
+	if r.Manifest.HasSessions && opts.Passphrase != "" {
+		n, err := r.importSessions(opts)
+		result.Sessions = n
+		if err != nil {
+			return &result, errors.Wrap(err, "failed to import sessions")
+		}
+	}
+
+	return &result, nil
+}
+
+func (r *Reader) importSessions(opts ImportOptions) (int, error) {
+	blob, err := r.readFile(sessionsFile)
+	if err != nil {
+		return 0, err
+	}
+
+	b, err := encrypted.OpenWithPassphrase(opts.Passphrase, sessionsName, blob)
+	if err != nil {
+		return 0, err
+	}
+
+	var sessions map[string][]keyring.Session
+	if err := json.Unmarshal(b, &sessions); err != nil {
+		return 0, errors.Wrap(err, "failed to unmarshal sessions")
+	}
+
+	svcs, _ := services.Get()
+	var imported int
+
+	for _, svc := range svcs {
+		incoming, ok := sessions[svc.ID()]
+		if !ok {
+			continue
+		}
+
+		service := keyring.Restore(svc)
+
+		for _, session := range incoming {
+			i := indexSession(service.Sessions, session.ID)
+			switch {
+			case i == -1:
+				service.Sessions = append(service.Sessions, session)
+			case opts.Conflict == Overwrite:
+				service.Sessions[i] = session
+			default:
+				continue
+			}
+			imported++
+		}
+
+		service.Save()
+	}
+
+	return imported, nil
+}
+
+func indexSession(sessions []keyring.Session, id string) int {
+	for i, session := range sessions {
+		if session.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// isSecret returns true if the file is a keyring secret file, which should
+// never be bundled.
+func isSecret(file string) bool {
+	return strings.HasSuffix(file, "_secret.json") || strings.HasSuffix(file, "_secret.enc")
+}
+
+// isBackup returns true if the file is a leftover backup or temporary file from
+// saving configs.
+func isBackup(file string) bool {
+	return strings.HasSuffix(file, config.BackupSuffix) ||
+		strings.HasPrefix(filepath.Base(file), ".")
+}
+
+// cleanPath verifies that the archive path stays inside the config directory
+// and converts it to a native path.
+func cleanPath(name string) (string, error) {
+	clean := path.Clean(name)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("invalid path %q in bundle", name)
+	}
+	return filepath.FromSlash(clean), nil
+}
+
+func (r *Reader) find(name string) (*zip.File, error) {
+	for _, f := range r.z.File {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("%s not found in bundle", name)
+}
+
+func (r *Reader) readFile(name string) ([]byte, error) {
+	f, err := r.find(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return ioutil.ReadAll(rc)
+}
+
+func (r *Reader) readJSON(name string, v interface{}) error {
+	b, err := r.readFile(name)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func writeJSON(z *zip.Writer, name string, v interface{}) error {
+	w, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+	return config.PrettyMarshal(w, v)
+}
+
+func copyIn(z *zip.Writer, name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func copyInWithoutSecrets(z *zip.Writer, name, src string) error {
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	b, err = config.WithoutSecrets(b)
+	if err != nil {
+		return errors.Wrap(err, "failed to remove secrets")
+	}
+
+	w, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// copyOut copies the archived file into the config directory. The existing file
+// is replaced atomically and kept as a backup.
+func copyOut(f *zip.File, rel string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+
+	return config.SaveToFile(rel, b)
+}
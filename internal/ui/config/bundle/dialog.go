@@ -0,0 +1,260 @@
+package bundle
+
+import (
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/diamondburned/cchat-gtk/internal/gts"
+	"github.com/diamondburned/cchat-gtk/internal/ui/dialog"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/gotk3/gotk3/pango"
+	"github.com/pkg/errors"
+)
+
+// SpawnExport shows the dialog to export the profile into a file.
+func SpawnExport() {
+	form := newForm()
+
+	include, _ := gtk.CheckButtonNewWithLabel("Include saved sessions (encrypted)")
+	include.Show()
+	form.Box.PackStart(include, false, false, 0)
+
+	pass := form.addPassword("Passphrase")
+	confirm := form.addPassword("Confirm passphrase")
+	pass.SetSensitive(false)
+	confirm.SetSensitive(false)
+
+	include.Connect("toggled", func(include *gtk.CheckButton) {
+		pass.SetSensitive(include.GetActive())
+		confirm.SetSensitive(include.GetActive())
+	})
+
+	m := dialog.NewModal(form, "Export Profile", "_Export", func(m *dialog.Modal) {
+		var opts ExportOptions
+
+		if include.GetActive() {
+			p, _ := pass.GetText()
+			c, _ := confirm.GetText()
+
+			switch {
+			case p == "":
+				form.setError(errors.New("passphrase must not be empty"))
+				return
+			case p != c:
+				form.setError(errors.New("passphrases do not match"))
+				return
+			}
+
+			opts.Passphrase = p
+		}
+
+		path := chooseFile(gtk.FILE_CHOOSER_ACTION_SAVE, "Export Profile", "Export")
+		if path == "" {
+			return
+		}
+
+		m.SetSensitive(false)
+
+		gts.Async(func() (func(), error) {
+			err := exportFile(path, opts)
+
+			return func() {
+				m.SetSensitive(true)
+
+				if err != nil {
+					form.setError(err)
+					return
+				}
+
+				m.Destroy()
+			}, err
+		})
+	})
+	m.Show()
+}
+
+// exportFile exports the profile into a temporary file first, then renames it
+// over the path, so a failed export never leaves a partial file behind.
+func exportFile(path string, opts ExportOptions) error {
+	// TempFile creates the file with 0600.
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary file")
+	}
+	// Clean up the temporary file on failure. This is a no-op after a
+	// successful rename.
+	defer os.Remove(f.Name())
+
+	if err := Export(f, opts); err != nil {
+		f.Close()
+		return errors.Wrap(err, "failed to export profile")
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return errors.Wrap(err, "failed to sync")
+	}
+
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "failed to close")
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return errors.Wrap(err, "failed to replace file")
+	}
+
+	return nil
+}
+
+// SpawnImport asks for a profile file, then shows the dialog to import it.
+func SpawnImport() {
+	path := chooseFile(gtk.FILE_CHOOSER_ACTION_OPEN, "Import Profile", "Open")
+	if path == "" {
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		showResult(gtk.MESSAGE_ERROR, err.Error())
+		return
+	}
+
+	s, err := f.Stat()
+	if err != nil {
+		f.Close()
+		showResult(gtk.MESSAGE_ERROR, err.Error())
+		return
+	}
+
+	r, err := Open(f, s.Size())
+	if err != nil {
+		f.Close()
+		showResult(gtk.MESSAGE_ERROR, err.Error())
+		return
+	}
+
+	form := newForm()
+
+	created, _ := gtk.LabelNew("Exported on " + r.Manifest.Created.Format("Jan 2, 2006 15:04"))
+	created.SetXAlign(0)
+	created.Show()
+	form.Box.PackStart(created, false, false, 0)
+
+	conflict, _ := gtk.ComboBoxTextNew()
+	conflict.Append("keep", "Keep existing files and sessions")
+	conflict.Append("overwrite", "Overwrite existing files and sessions")
+	conflict.SetActive(int(KeepExisting))
+	conflict.Show()
+	form.Box.PackStart(conflict, false, false, 0)
+
+	var pass *gtk.Entry
+	if r.Manifest.HasSessions {
+		pass = form.addPassword("Passphrase for saved sessions (optional)")
+	}
+
+	m := dialog.NewModal(form, "Import Profile", "_Import", func(m *dialog.Modal) {
+		var opts = ImportOptions{
+			Conflict: Conflict(conflict.GetActive()),
+		}
+		if pass != nil {
+			opts.Passphrase, _ = pass.GetText()
+		}
+
+		m.SetSensitive(false)
+
+		gts.Async(func() (func(), error) {
+			result, err := r.Import(opts)
+
+			return func() {
+				m.SetSensitive(true)
+
+				if err != nil {
+					form.setError(err)
+					return
+				}
+
+				m.Destroy()
+				showResult(gtk.MESSAGE_INFO, fmt.Sprintf(
+					"Imported %d files and %d sessions, skipped %d existing files. "+
+						"Restart cchat-gtk to apply the imported profile.",
+					len(result.Written), result.Sessions, len(result.Skipped),
+				))
+			}, err
+		})
+	})
+	m.Connect("destroy", func(interface{}) { f.Close() })
+	m.Show()
+}
+
+func chooseFile(action gtk.FileChooserAction, title, accept string) string {
+	fc, _ := gtk.FileChooserNativeDialogNew(title, gts.App.Window, action, accept, "Cancel")
+	defer fc.Destroy()
+
+	filter, _ := gtk.FileFilterNew()
+	filter.SetName("cchat-gtk profile")
+	filter.AddPattern("*" + FileExt)
+	fc.AddFilter(filter)
+
+	if action == gtk.FILE_CHOOSER_ACTION_SAVE {
+		fc.SetDoOverwriteConfirmation(true)
+		fc.SetCurrentName("cchat-gtk" + FileExt)
+		fc.SetCurrentFolder(glib.GetHomeDir())
+	}
+
+	if fc.Run() != int(gtk.RESPONSE_ACCEPT) {
+		return ""
+	}
+
+	return fc.GetFilename()
+}
+
+func showResult(mtype gtk.MessageType, msg string) {
+	d := gtk.MessageDialogNew(
+		gts.App.Window, gtk.DIALOG_MODAL|gtk.DIALOG_DESTROY_WITH_PARENT,
+		mtype, gtk.BUTTONS_OK, "%s", msg,
+	)
+	d.Connect("response", func(interface{}) { d.Destroy() })
+	d.Show()
+}
+
+type form struct {
+	*gtk.Box
+	errLabel *gtk.Label
+}
+
+func newForm() *form {
+	errLabel, _ := gtk.LabelNew("")
+	errLabel.SetXAlign(0)
+	errLabel.SetLineWrap(true)
+	errLabel.SetLineWrapMode(pango.WRAP_WORD_CHAR)
+
+	box, _ := gtk.BoxNew(gtk.ORIENTATION_VERTICAL, 8)
+	box.SetMarginTop(8)
+	box.SetMarginBottom(8)
+	box.SetMarginStart(16)
+	box.SetMarginEnd(16)
+	box.PackEnd(errLabel, false, false, 0)
+	box.Show()
+
+	return &form{box, errLabel}
+}
+
+func (f *form) addPassword(placeholder string) *gtk.Entry {
+	e, _ := gtk.EntryNew()
+	e.SetVisibility(false)
+	e.SetInputPurpose(gtk.INPUT_PURPOSE_PASSWORD)
+	e.SetPlaceholderText(placeholder)
+	e.Show()
+
+	f.Box.PackStart(e, false, false, 0)
+	return e
+}
+
+func (f *form) setError(err error) {
+	f.errLabel.SetMarkup(`<span color="red">Error:</span> ` + html.EscapeString(err.Error()))
+	f.errLabel.Show()
+}
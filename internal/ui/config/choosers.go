@@ -0,0 +1,250 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/gotk3/gotk3/gdk"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/pkg/errors"
+)
+
+type _choice struct {
+	value   *string
+	options []string
+	change  func(string) error
+}
+
+// Choice creates a combo box that picks one of the given options. Unlike Combo,
+// the option itself is stored instead of its index, so options can be added or
+// reordered later.
+func Choice(value *string, options []string, change func(string) error) EntryValue {
+	return &_choice{value, options, change}
+}
+
+func (c *_choice) set(v string) error {
+	if !c.has(v) {
+		return fmt.Errorf("unknown option %q", v)
+	}
+
+	// The value is set before calling change, since it may read the value
+	// back. It's restored if the change is rejected.
+	old := *c.value
+	*c.value = v
+
+	if c.change != nil {
+		if err := c.change(v); err != nil {
+			*c.value = old
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *_choice) has(v string) bool {
+	for _, opt := range c.options {
+		if opt == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *_choice) Construct() gtk.IWidget {
+	combo, _ := gtk.ComboBoxTextNew()
+	for _, opt := range c.options {
+		combo.Append(opt, opt)
+	}
+	combo.SetActiveID(*c.value)
+	combo.Show()
+
+	fb := newFeedback(combo)
+
+	combo.Connect("changed", func(combo *gtk.ComboBoxText) {
+		fb.set(c.set(combo.GetActiveID()))
+	})
+
+	return fb
+}
+
+func (c *_choice) MarshalJSON() ([]byte, error) {
+	return json.Marshal(*c.value)
+}
+
+func (c *_choice) UnmarshalJSON(b []byte) error {
+	var value string
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	return c.set(value)
+}
+
+type _color struct {
+	value  *string
+	change func(string)
+}
+
+// Color creates a color picker. The color is stored as a string that
+// gdk.RGBA.Parse understands, such as "#ff0000" or "rgba(0,0,0,0.5)". An empty
+// string means no color.
+func Color(value *string, change func(string)) EntryValue {
+	return &_color{value, change}
+}
+
+func (c *_color) set(v string) error {
+	if v != "" && !gdk.NewRGBA().Parse(v) {
+		return fmt.Errorf("invalid color %q", v)
+	}
+
+	*c.value = v
+	if c.change != nil {
+		c.change(v)
+	}
+	return nil
+}
+
+func (c *_color) Construct() gtk.IWidget {
+	rgba := gdk.NewRGBA()
+	rgba.Parse(*c.value)
+
+	button, _ := gtk.ColorButtonNewWithRGBA(rgba)
+	button.SetUseAlpha(true)
+	button.Show()
+
+	fb := newFeedback(button)
+
+	button.Connect("color-set", func(button *gtk.ColorButton) {
+		fb.set(c.set(button.GetRGBA().String()))
+	})
+
+	return fb
+}
+
+func (c *_color) MarshalJSON() ([]byte, error) {
+	return json.Marshal(*c.value)
+}
+
+func (c *_color) UnmarshalJSON(b []byte) error {
+	var value string
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	return c.set(value)
+}
+
+type _font struct {
+	value  *string
+	change func(string)
+}
+
+// Font creates a font chooser. The font is stored as a Pango font description
+// string, such as "Sans Bold 12". An empty string means the default font.
+func Font(value *string, change func(string)) EntryValue {
+	return &_font{value, change}
+}
+
+func (f *_font) set(v string) {
+	*f.value = v
+	if f.change != nil {
+		f.change(v)
+	}
+}
+
+func (f *_font) Construct() gtk.IWidget {
+	button, _ := gtk.FontButtonNewWithFont(*f.value)
+	button.Connect("font-set", func(button *gtk.FontButton) { f.set(button.GetFont()) })
+	button.SetHAlign(gtk.ALIGN_END)
+	button.Show()
+
+	return button
+}
+
+func (f *_font) MarshalJSON() ([]byte, error) {
+	return json.Marshal(*f.value)
+}
+
+func (f *_font) UnmarshalJSON(b []byte) error {
+	var value string
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	f.set(value)
+	return nil
+}
+
+type _path struct {
+	value  *string
+	dir    bool
+	change func(string)
+}
+
+// File creates a chooser for an existing file. An empty string means no file.
+func File(value *string, change func(string)) EntryValue {
+	return &_path{value, false, change}
+}
+
+// Directory creates a chooser for an existing directory. An empty string means
+// no directory.
+func Directory(value *string, change func(string)) EntryValue {
+	return &_path{value, true, change}
+}
+
+func (p *_path) set(v string) error {
+	if v != "" {
+		s, err := os.Stat(v)
+		if err != nil {
+			return errors.Wrap(err, "failed to stat")
+		}
+
+		switch {
+		case p.dir && !s.IsDir():
+			return fmt.Errorf("%s is not a directory", v)
+		case !p.dir && !s.Mode().IsRegular():
+			return fmt.Errorf("%s is not a file", v)
+		}
+	}
+
+	*p.value = v
+	if p.change != nil {
+		p.change(v)
+	}
+	return nil
+}
+
+func (p *_path) Construct() gtk.IWidget {
+	var action = gtk.FILE_CHOOSER_ACTION_OPEN
+	var title = "Choose a File"
+	if p.dir {
+		action = gtk.FILE_CHOOSER_ACTION_SELECT_FOLDER
+		title = "Choose a Directory"
+	}
+
+	button, _ := gtk.FileChooserButtonNew(title, action)
+	button.SetSizeRequest(200, -1)
+	if *p.value != "" {
+		button.SetFilename(*p.value)
+	}
+	button.Show()
+
+	fb := newFeedback(button)
+
+	button.Connect("file-set", func(button *gtk.FileChooserButton) {
+		fb.set(p.set(button.GetFilename()))
+	})
+
+	return fb
+}
+
+func (p *_path) MarshalJSON() ([]byte, error) {
+	return json.Marshal(*p.value)
+}
+
+func (p *_path) UnmarshalJSON(b []byte) error {
+	var value string
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	return p.set(value)
+}
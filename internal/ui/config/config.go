@@ -2,7 +2,9 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"sort"
 
 	"github.com/diamondburned/cchat-gtk/internal/log"
@@ -11,11 +13,16 @@ import (
 
 const ConfigFile = "config.json"
 
-// List of config sections.
+// List of config sections. Sections that nothing is registered into, such as
+// Notifications for now, aren't shown in the preferences.
 type Section uint8
 
 const (
 	Appearance Section = iota
+	Behavior
+	Notifications
+	Network
+	Privacy
 	sectionLen
 )
 
@@ -23,6 +30,14 @@ func (s Section) String() string {
 	switch s {
 	case Appearance:
 		return "Appearance"
+	case Behavior:
+		return "Behavior"
+	case Notifications:
+		return "Notifications"
+	case Network:
+		return "Network"
+	case Privacy:
+		return "Privacy"
 	default:
 		return "???"
 	}
@@ -30,48 +45,69 @@ func (s Section) String() string {
 
 type SectionEntries map[string]EntryValue
 
-// UnmarshalJSON ignores all JSON entries with unknown keys.
+// UnmarshalJSON ignores all JSON entries with unknown keys. Unknown keys are
+// logged, since they should've been handled by a Migration.
 func (s SectionEntries) UnmarshalJSON(b []byte) error {
 	var entries map[string]json.RawMessage
 	if err := json.Unmarshal(b, &entries); err != nil {
 		return err
 	}
 
-	for k, entry := range s {
-		v, ok := entries[k]
-		if ok {
-			if err := entry.UnmarshalJSON(v); err != nil {
-				// Non-fatal error.
-				log.Error(errors.Wrapf(err, "Failed to unmarshal key %q", k))
-			}
+	s.unmarshalEntries(entries)
+	return nil
+}
+
+func (s SectionEntries) unmarshalEntries(entries map[string]json.RawMessage) {
+	for k, v := range entries {
+		entry, ok := s[k]
+		if !ok {
+			log.Info(fmt.Errorf("Ignoring unknown config key %q", k))
+			continue
 		}
-	}
 
-	return nil
+		if err := entry.UnmarshalJSON(v); err != nil {
+			// Non-fatal error.
+			log.Error(errors.Wrapf(err, "Failed to unmarshal key %q", k))
+		}
+	}
 }
 
 var sections = [sectionLen]SectionEntries{}
 
-func AppearanceAdd(name string, value EntryValue) {
-	sc := sections[Appearance]
+// Register adds the entry value into the given section under the given name.
+// The name is also used as the key in the config file, so changing it requires
+// a Migration. It should be called in init() before Restore is called.
+func Register(section Section, name string, value EntryValue) {
+	sc := sections[section]
 	if sc == nil {
 		sc = make(SectionEntries, 1)
-		sections[Appearance] = sc
+		sections[section] = sc
 	}
 
 	sc[name] = value
 }
 
+// AppearanceAdd adds the entry value into the Appearance section.
+func AppearanceAdd(name string, value EntryValue) {
+	Register(Appearance, name, value)
+}
+
 type Entry struct {
-	Name  string
-	Value EntryValue
+	Name        string
+	Description string
+	Value       EntryValue
 }
 
 func Sections() (sects [sectionLen][]Entry) {
 	for i, section := range sections {
 		var sect = make([]Entry, 0, len(section))
 		for k, v := range section {
-			sect = append(sect, Entry{k, v})
+			var desc string
+			if describer, ok := v.(Describer); ok {
+				desc = describer.Description()
+			}
+
+			sect = append(sect, Entry{k, desc, v})
 		}
 
 		sort.Slice(sect, func(i, j int) bool {
@@ -86,15 +122,77 @@ func Sections() (sects [sectionLen][]Entry) {
 
 // Save the global config.
 func Save() error {
-	return MarshalToFile(ConfigFile, sections)
+	var file = fileConfig{
+		Version:  schemaVersion,
+		Sections: make(RawConfig, len(sections)),
+	}
+
+	for i, section := range sections {
+		var entries = make(map[string]json.RawMessage, len(section))
+
+		for k, v := range section {
+			// Never save the overriding value in place of the global one.
+			if b, ok := globalValue(Section(i), k); ok {
+				entries[k] = b
+				continue
+			}
+
+			b, err := v.MarshalJSON()
+			if err != nil {
+				return errors.Wrapf(err, "failed to marshal key %q", k)
+			}
+			entries[k] = b
+		}
+
+		file.Sections[Section(i).String()] = entries
+	}
+
+	return MarshalToFile(ConfigFile, file)
+}
+
+// WithoutSecrets returns the content of the main config file with the secret
+// entries, such as passwords, removed, so that it can be shared. The config is
+// migrated to the current schema version.
+func WithoutSecrets(b []byte) ([]byte, error) {
+	rawConfig, err := decodeRaw(b)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, section := range sections {
+		entries := rawConfig[Section(i).String()]
+
+		for k, v := range section {
+			if isSecret(v) {
+				delete(entries, k)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := PrettyMarshal(&buf, fileConfig{schemaVersion, rawConfig}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// isSecret returns true if the entry value holds a secret, which is any
+// PasswordEntry.
+func isSecret(v EntryValue) bool {
+	if described, ok := v.(_described); ok {
+		v = described.EntryValue
+	}
+
+	entry, ok := v.(*_inputentry)
+	return ok && entry.hidden
 }
 
 // Restore the global config. IsNotExist is not an error and will not be
-// logged.
+// logged. Old configs are migrated to the current schema version.
 func Restore() {
-	if err := UnmarshalFromFile(ConfigFile, &sections); err != nil {
-		log.Error(errors.Wrap(err, "Failed to unmarshal main config.json"))
-	}
+	saveDefaults()
+	restoreMain(false)
 
 	for path, v := range toRestore {
 		if err := UnmarshalFromFile(path, v); err != nil {
@@ -103,12 +201,106 @@ func Restore() {
 	}
 }
 
+// defaults contains the values of the entries from before the main config was
+// first restored.
+var defaults RawConfig
+
+// saveDefaults marshals the current values of the entries into defaults.
+func saveDefaults() {
+	defaults = make(RawConfig, len(sections))
+
+	for i, section := range sections {
+		var values = make(map[string]json.RawMessage, len(section))
+
+		for name, entry := range section {
+			v, err := entry.MarshalJSON()
+			if err != nil {
+				log.Error(errors.Wrapf(err, "Failed to marshal key %q", name))
+				continue
+			}
+			values[name] = v
+		}
+
+		defaults[Section(i).String()] = values
+	}
+}
+
+// restoreMain restores the main config. If reset is true, then the entries
+// that aren't in the file are reset to their defaults, which is done when the
+// file is reloaded.
+func restoreMain(reset bool) {
+	// Restore the global values underneath the overrides.
+	defer SuspendOverrides()()
+
+	var raw json.RawMessage
+
+	if err := UnmarshalFromFile(ConfigFile, &raw); err != nil {
+		log.Error(errors.Wrap(err, "Failed to unmarshal main config.json"))
+		return
+	}
+
+	var rawConfig = RawConfig{}
+
+	if len(raw) > 0 {
+		c, err := decodeRaw(raw)
+		if err != nil {
+			log.Error(errors.Wrap(err, "Failed to restore main config.json"))
+			return
+		}
+		rawConfig = c
+	}
+
+	if reset {
+		rawConfig = withDefaults(rawConfig)
+	}
+
+	for i, section := range sections {
+		if entries, ok := rawConfig[Section(i).String()]; ok {
+			section.unmarshalEntries(entries)
+		}
+	}
+}
+
+// withDefaults returns the raw config with the defaults added for the entries
+// that it doesn't have.
+func withDefaults(raw RawConfig) RawConfig {
+	var merged = make(RawConfig, len(defaults))
+
+	for sectName, values := range defaults {
+		var sect = make(map[string]json.RawMessage, len(values))
+		for name, v := range values {
+			sect[name] = v
+		}
+		for name, v := range raw[sectName] {
+			sect[name] = v
+		}
+
+		merged[sectName] = sect
+	}
+
+	return merged
+}
+
 var toRestore = map[string]interface{}{}
 
+// reloaders contains the callbacks of the configs in toRestore that are called
+// after they're reloaded.
+var reloaders = map[string]*Updaters{}
+
 // RegisterConfig adds the config filename into the registry of value pointers
-// to unmarshal configs to.
-func RegisterConfig(filename string, jsonValue interface{}) {
+// to unmarshal configs to. The given callbacks are called in the main thread
+// after the file is reloaded because it was changed on disk, so that the new
+// values can be applied.
+func RegisterConfig(filename string, jsonValue interface{}, reloaded ...func()) {
 	toRestore[filename] = jsonValue
+
+	if len(reloaded) > 0 {
+		us := &Updaters{}
+		for _, f := range reloaded {
+			us.Add(f)
+		}
+		reloaders[filename] = us
+	}
 }
 
 // Updaters contains a list of callbacks to be called when something is updated.
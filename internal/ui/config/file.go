@@ -1,13 +1,16 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"sync"
 
+	"github.com/diamondburned/cchat-gtk/internal/profile"
 	"github.com/pkg/errors"
 )
 
@@ -19,17 +22,20 @@ var dirPath string
 var __initonce sync.Once
 
 func __init() {
-	// Load the config dir:
-	d, err := os.UserConfigDir()
-	if err != nil {
-		log.Fatalln("Failed to get config dir:", err)
-	}
+	// Use the overridden config dir as-is if there's one:
+	if dirPath = profile.ConfigDir(); dirPath == "" {
+		// Load the config dir:
+		d, err := os.UserConfigDir()
+		if err != nil {
+			log.Fatalln("Failed to get config dir:", err)
+		}
 
-	// Fill Path:
-	dirPath = filepath.Join(d, "cchat-gtk")
+		// Fill Path, which is namespaced by the profile:
+		dirPath = filepath.Join(d, profile.Namespace("cchat-gtk"))
+	}
 
 	// Ensure it exists:
-	if err := os.Mkdir(dirPath, 0755|os.ModeDir); err != nil && !os.IsExist(err) {
+	if err := os.MkdirAll(dirPath, 0755|os.ModeDir); err != nil {
 		log.Fatalln("Failed to make config dir:", err)
 	}
 }
@@ -49,42 +55,71 @@ func DirPath() string {
 	return dirPath
 }
 
+// BackupSuffix is the suffix of the backup file that keeps the previous version
+// of a config file.
+const BackupSuffix = ".bak"
+
 // SaveToFile saves the given bytes into the given filename. The filename will
-// be prepended with the config directory.
+// be prepended with the config directory. The file is replaced atomically, and
+// the previous version is kept as a backup.
 func SaveToFile(file string, v []byte) error {
-	file = filepath.Join(DirPath(), file)
-
-	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_SYNC|os.O_TRUNC, 0644)
-	if err != nil {
-		return errors.Wrap(err, "failed to open file")
-	}
-	defer f.Close()
-
-	if _, err := f.Write(v); err != nil {
-		return errors.Wrap(err, "failed to write")
-	}
-
-	return nil
+	return writeFile(filepath.Join(DirPath(), file), v)
 }
 
 // MarshalToFile marshals the given interface into the given filename. The
-// filename will be prepended with the config directory.
+// filename will be prepended with the config directory. The file is replaced
+// atomically, and the previous version is kept as a backup.
 func MarshalToFile(file string, from interface{}) error {
-	file = filepath.Join(DirPath(), file)
+	var buf bytes.Buffer
+	if err := PrettyMarshal(&buf, from); err != nil {
+		return errors.Wrap(err, "failed to marshal given struct")
+	}
+
+	return writeFile(filepath.Join(DirPath(), file), buf.Bytes())
+}
 
+// writeFile atomically writes v into the given path by writing into a
+// temporary file first, then renaming it over the destination. A crash midway
+// will therefore never leave a truncated file.
+func writeFile(file string, v []byte) error {
 	dir := filepath.Dir(file)
 	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
 		return errors.Wrap(err, "failed to create config dir")
 	}
 
-	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_SYNC|os.O_TRUNC, 0644)
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(file)+".tmp*")
 	if err != nil {
-		return errors.Wrap(err, "failed to open file")
+		return errors.Wrap(err, "failed to create temporary file")
 	}
-	defer f.Close()
+	// Clean up the temporary file on failure. This is a no-op after a
+	// successful rename.
+	defer os.Remove(f.Name())
 
-	if err := PrettyMarshal(f, from); err != nil {
-		return errors.Wrap(err, "failed to marshal given struct")
+	if _, err := f.Write(v); err != nil {
+		f.Close()
+		return errors.Wrap(err, "failed to write")
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return errors.Wrap(err, "failed to sync")
+	}
+
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "failed to close")
+	}
+
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return errors.Wrap(err, "failed to chmod")
+	}
+
+	// Keep the old file as a backup. Hard linking means the backup is never
+	// partially written. This is best-effort, so errors are ignored.
+	os.Remove(file + BackupSuffix)
+	os.Link(file, file+BackupSuffix)
+
+	if err := replaceWritten(f.Name(), file); err != nil {
+		return errors.Wrap(err, "failed to replace file")
 	}
 
 	return nil
@@ -92,16 +127,27 @@ func MarshalToFile(file string, from interface{}) error {
 
 // UnmarshalFromFile unmarshals the given filename to the given interface. The
 // filename will be prepended with the config directory. IsNotExist errors are
-// ignored.
+// ignored. If the file is corrupted, then the backup is tried.
 func UnmarshalFromFile(file string, to interface{}) error {
 	file = filepath.Join(DirPath(), file)
 
+	err := unmarshalFile(file, to)
+	if err == nil || os.IsNotExist(errors.Cause(err)) {
+		return nil
+	}
+
+	// Try the backup, but return the original error regardless, since the
+	// user should know that they've lost the latest changes.
+	if bakErr := unmarshalFile(file+BackupSuffix, to); bakErr == nil {
+		return errors.Wrap(err, "restored from backup")
+	}
+
+	return err
+}
+
+func unmarshalFile(file string, to interface{}) error {
 	f, err := os.OpenFile(file, os.O_RDONLY, 0644)
 	if err != nil {
-		// Ignore does not exist error, leave struct as it is.
-		if os.IsNotExist(err) {
-			return nil
-		}
 		return err
 	}
 	defer f.Close()
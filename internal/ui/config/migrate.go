@@ -0,0 +1,136 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// RawConfig is the undecoded main config. It maps section names to entry names
+// to their raw JSON values.
+type RawConfig map[string]map[string]json.RawMessage
+
+// Migration upgrades the raw config from the previous schema version. It is
+// called before any value is unmarshaled into its entry.
+type Migration func(RawConfig) error
+
+// migrations maps schema versions to the migration that upgrades to it.
+var migrations = map[int]Migration{}
+
+// schemaVersion is the current schema version. Version 0 is the unversioned
+// format, which is a plain array of sections.
+var schemaVersion = 1
+
+// RegisterMigration registers a migration that upgrades configs of the version
+// right before the given one. Registering a version higher than the current
+// schema version bumps it. It panics if the version is already registered.
+func RegisterMigration(version int, m Migration) {
+	if version <= 1 {
+		panic(fmt.Sprintf("migration version %d is reserved", version))
+	}
+	if _, ok := migrations[version]; ok {
+		panic(fmt.Sprintf("migration version %d already registered", version))
+	}
+
+	migrations[version] = m
+
+	if version > schemaVersion {
+		schemaVersion = version
+	}
+}
+
+// RenameEntry returns a migration that renames an entry within a section. It
+// is useful for when the name given to AppearanceAdd changes.
+func RenameEntry(section Section, from, to string) Migration {
+	return func(raw RawConfig) error {
+		entries, ok := raw[section.String()]
+		if !ok {
+			return nil
+		}
+
+		if v, ok := entries[from]; ok {
+			entries[to] = v
+			delete(entries, from)
+		}
+
+		return nil
+	}
+}
+
+// RemoveEntry returns a migration that removes an entry from a section.
+func RemoveEntry(section Section, name string) Migration {
+	return func(raw RawConfig) error {
+		delete(raw[section.String()], name)
+		return nil
+	}
+}
+
+// fileConfig is the format of config.json since version 1.
+type fileConfig struct {
+	Version  int       `json:"version"`
+	Sections RawConfig `json:"sections"`
+}
+
+// decodeRaw decodes the main config of any version into a raw config of the
+// current schema version.
+func decodeRaw(b []byte) (RawConfig, error) {
+	var file fileConfig
+
+	if isArray(b) {
+		// Version 0 is an array of sections ordered by the Section enum.
+		var sects []map[string]json.RawMessage
+		if err := json.Unmarshal(b, &sects); err != nil {
+			return nil, err
+		}
+
+		file.Sections = make(RawConfig, len(sects))
+		for i, sect := range sects {
+			file.Sections[Section(i).String()] = sect
+		}
+	} else {
+		if err := json.Unmarshal(b, &file); err != nil {
+			return nil, err
+		}
+
+		if file.Version > schemaVersion {
+			return nil, fmt.Errorf(
+				"config version %d is newer than supported version %d",
+				file.Version, schemaVersion,
+			)
+		}
+	}
+
+	if file.Sections == nil {
+		file.Sections = RawConfig{}
+	}
+
+	var versions = make([]int, 0, len(migrations))
+	for v := range migrations {
+		if v > file.Version {
+			versions = append(versions, v)
+		}
+	}
+	sort.Ints(versions)
+
+	for _, v := range versions {
+		if err := migrations[v](file.Sections); err != nil {
+			return nil, errors.Wrapf(err, "failed to migrate config to version %d", v)
+		}
+	}
+
+	return file.Sections, nil
+}
+
+func isArray(b []byte) bool {
+	for _, c := range b {
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		default:
+			return c == '['
+		}
+	}
+	return false
+}
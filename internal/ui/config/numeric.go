@@ -0,0 +1,108 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gotk3/gotk3/gtk"
+)
+
+type _spin struct {
+	value    *int
+	min, max int
+	change   func(int)
+}
+
+// Spin creates a spin button for an integer within the given inclusive bounds.
+func Spin(value *int, min, max int, change func(int)) EntryValue {
+	return &_spin{value, min, max, change}
+}
+
+func (s *_spin) set(v int) error {
+	if v < s.min || v > s.max {
+		return fmt.Errorf("must be between %d and %d", s.min, s.max)
+	}
+
+	*s.value = v
+	if s.change != nil {
+		s.change(v)
+	}
+	return nil
+}
+
+func (s *_spin) Construct() gtk.IWidget {
+	spin, _ := gtk.SpinButtonNewWithRange(float64(s.min), float64(s.max), 1)
+	spin.SetValue(float64(*s.value))
+	spin.Show()
+
+	fb := newFeedback(spin)
+
+	spin.Connect("value-changed", func(spin *gtk.SpinButton) {
+		fb.set(s.set(spin.GetValueAsInt()))
+	})
+
+	return fb
+}
+
+func (s *_spin) MarshalJSON() ([]byte, error) {
+	return json.Marshal(*s.value)
+}
+
+func (s *_spin) UnmarshalJSON(b []byte) error {
+	var value int
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	return s.set(value)
+}
+
+type _slider struct {
+	value          *float64
+	min, max, step float64
+	change         func(float64)
+}
+
+// Slider creates a horizontal slider for a number within the given inclusive
+// bounds.
+func Slider(value *float64, min, max, step float64, change func(float64)) EntryValue {
+	return &_slider{value, min, max, step, change}
+}
+
+func (s *_slider) set(v float64) error {
+	if v < s.min || v > s.max {
+		return fmt.Errorf("must be between %g and %g", s.min, s.max)
+	}
+
+	*s.value = v
+	if s.change != nil {
+		s.change(v)
+	}
+	return nil
+}
+
+func (s *_slider) Construct() gtk.IWidget {
+	scale, _ := gtk.ScaleNewWithRange(gtk.ORIENTATION_HORIZONTAL, s.min, s.max, s.step)
+	scale.SetValue(*s.value)
+	scale.SetSizeRequest(150, -1)
+	scale.Show()
+
+	fb := newFeedback(scale)
+
+	scale.Connect("value-changed", func(scale *gtk.Scale) {
+		fb.set(s.set(scale.GetValue()))
+	})
+
+	return fb
+}
+
+func (s *_slider) MarshalJSON() ([]byte, error) {
+	return json.Marshal(*s.value)
+}
+
+func (s *_slider) UnmarshalJSON(b []byte) error {
+	var value float64
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	return s.set(value)
+}
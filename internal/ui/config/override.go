@@ -0,0 +1,108 @@
+package config
+
+import (
+	"encoding/json"
+
+	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/pkg/errors"
+)
+
+// overrides keeps track of the entries that are currently overridden. It is
+// only accessed from the main thread.
+var overrides struct {
+	active  RawConfig // the overriding values
+	globals RawConfig // the global values to revert to
+}
+
+// Override temporarily replaces the values of the given entries, such as when a
+// channel with its own settings is opened. The previous overrides are reverted
+// first, and passing nil only reverts them. Save always writes the global
+// values, regardless of any override. This function is not thread-safe.
+func Override(raw RawConfig) {
+	revertOverrides()
+
+	if len(raw) == 0 {
+		return
+	}
+
+	var globals = make(RawConfig, len(raw))
+
+	for sectName, values := range raw {
+		section := sectionNamed(sectName)
+		if section == nil {
+			continue
+		}
+
+		var sectGlobals = make(map[string]json.RawMessage, len(values))
+
+		for name, v := range values {
+			entry, ok := section[name]
+			if !ok {
+				continue
+			}
+
+			global, err := entry.MarshalJSON()
+			if err != nil {
+				log.Error(errors.Wrapf(err, "Failed to marshal key %q", name))
+				continue
+			}
+
+			if err := entry.UnmarshalJSON(v); err != nil {
+				log.Error(errors.Wrapf(err, "Failed to override key %q", name))
+				continue
+			}
+
+			sectGlobals[name] = global
+		}
+
+		globals[sectName] = sectGlobals
+	}
+
+	overrides.active = raw
+	overrides.globals = globals
+}
+
+// Overrides returns the currently active overrides. The returned value must not
+// be modified.
+func Overrides() RawConfig {
+	return overrides.active
+}
+
+// SuspendOverrides reverts all overrides until the returned callback is called,
+// which applies them again. This is useful for editing the global values.
+func SuspendOverrides() (resume func()) {
+	active := overrides.active
+	Override(nil)
+
+	return func() { Override(active) }
+}
+
+func revertOverrides() {
+	for sectName, values := range overrides.globals {
+		section := sectionNamed(sectName)
+
+		for name, v := range values {
+			if err := section[name].UnmarshalJSON(v); err != nil {
+				log.Error(errors.Wrapf(err, "Failed to revert key %q", name))
+			}
+		}
+	}
+
+	overrides.active = nil
+	overrides.globals = nil
+}
+
+// globalValue returns the global value of the given entry if it's overridden.
+func globalValue(section Section, name string) (json.RawMessage, bool) {
+	v, ok := overrides.globals[section.String()][name]
+	return v, ok
+}
+
+func sectionNamed(name string) SectionEntries {
+	for i, section := range sections {
+		if Section(i).String() == name {
+			return section
+		}
+	}
+	return nil
+}
@@ -3,9 +3,11 @@ package preferences
 import (
 	"github.com/diamondburned/cchat-gtk/internal/log"
 	"github.com/diamondburned/cchat-gtk/internal/ui/config"
+	"github.com/diamondburned/cchat-gtk/internal/ui/credentials"
 	"github.com/diamondburned/cchat-gtk/internal/ui/dialog"
 	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
 	"github.com/gotk3/gotk3/gtk"
+	"github.com/gotk3/gotk3/pango"
 	"github.com/pkg/errors"
 )
 
@@ -44,16 +46,41 @@ func NewDialog() *Dialog {
 	}
 }
 
+var descriptionCSS = primitives.PrepareClassCSS("config-description", `
+	.config-description {
+		font-size: 0.85em;
+		opacity: 0.65;
+	}
+`)
+
 func Section(entries []config.Entry) *gtk.Grid {
 	var grid, _ = gtk.GridNew()
 
 	for i, entry := range entries {
 		l, _ := gtk.LabelNew(entry.Name)
-		l.SetHExpand(true)
 		l.SetXAlign(0)
 		l.Show()
 
-		grid.Attach(l, 0, i, 1, 1)
+		name, _ := gtk.BoxNew(gtk.ORIENTATION_VERTICAL, 0)
+		name.SetHExpand(true)
+		name.SetVAlign(gtk.ALIGN_CENTER)
+		name.PackStart(l, false, false, 0)
+		name.Show()
+
+		if entry.Description != "" {
+			name.SetTooltipText(entry.Description)
+
+			d, _ := gtk.LabelNew(entry.Description)
+			d.SetXAlign(0)
+			d.SetLineWrap(true)
+			d.SetLineWrapMode(pango.WRAP_WORD_CHAR)
+			d.Show()
+			descriptionCSS(d)
+
+			name.PackStart(d, false, false, 0)
+		}
+
+		grid.Attach(name, 0, i, 1, 1)
 		grid.Attach(entry.Value.Construct(), 1, i, 1, 1)
 	}
 
@@ -70,22 +97,44 @@ func NewPreferenceDialog() *Dialog {
 	var dialog = NewDialog()
 
 	for i, section := range config.Sections() {
+		// Skip sections that nothing registered into.
+		if len(section) == 0 {
+			continue
+		}
+
 		grid := Section(section)
+		grid.SetVAlign(gtk.ALIGN_START)
 		name := config.Section(i).String()
 
-		dialog.stack.AddTitled(grid, name, name)
+		sw, _ := gtk.ScrolledWindowNew(nil, nil)
+		sw.SetPolicy(gtk.POLICY_NEVER, gtk.POLICY_AUTOMATIC)
+		sw.Add(grid)
+		sw.Show()
+
+		dialog.stack.AddTitled(sw, name, name)
 	}
 
+	creds := credentials.NewPage()
+	dialog.stack.AddTitled(creds, "Credentials", "Credentials")
+
+	storage := NewStoragePage()
+	dialog.stack.AddTitled(storage, "Storage", "Storage")
+
 	return dialog
 }
 
 func SpawnPreferenceDialog() {
+	// Show and edit the global values instead of the opened channel's.
+	resume := config.SuspendOverrides()
+
 	p := NewPreferenceDialog()
 	p.Connect("destroy", func(interface{}) {
 		// On close, save the settings.
 		if err := config.Save(); err != nil {
 			log.Error(errors.Wrap(err, "Failed to save settings"))
 		}
+
+		resume()
 	})
 	p.Show()
 }
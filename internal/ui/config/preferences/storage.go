@@ -0,0 +1,63 @@
+package preferences
+
+import (
+	"fmt"
+
+	"github.com/diamondburned/cchat-gtk/internal/gts"
+	"github.com/diamondburned/cchat-gtk/internal/gts/httputil"
+	"github.com/gotk3/gotk3/gtk"
+)
+
+// StoragePage shows the media cache statistics and allows clearing it.
+type StoragePage struct {
+	*gtk.Box
+	Stats *gtk.Label
+	Clear *gtk.Button
+}
+
+// NewStoragePage creates a new storage page. The statistics are refreshed
+// every time the page is shown.
+func NewStoragePage() *StoragePage {
+	p := &StoragePage{}
+
+	p.Stats, _ = gtk.LabelNew("")
+	p.Stats.SetXAlign(0)
+	p.Stats.SetHExpand(true)
+	p.Stats.Show()
+
+	p.Clear, _ = gtk.ButtonNewWithLabel("Clear media cache")
+	p.Clear.SetVAlign(gtk.ALIGN_CENTER)
+	p.Clear.Connect("clicked", func(*gtk.Button) {
+		p.Clear.SetSensitive(false)
+
+		gts.Async(func() (func(), error) {
+			httputil.ClearMediaCache()
+
+			return func() {
+				p.Clear.SetSensitive(true)
+				p.Refresh()
+			}, nil
+		})
+	})
+	p.Clear.Show()
+
+	p.Box, _ = gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 8)
+	p.Box.SetVAlign(gtk.ALIGN_START)
+	p.Box.PackStart(p.Stats, true, true, 0)
+	p.Box.PackStart(p.Clear, false, false, 0)
+	p.Box.Connect("map", func(*gtk.Box) { p.Refresh() })
+	p.Box.Show()
+
+	return p
+}
+
+// Refresh updates the statistics.
+func (p *StoragePage) Refresh() {
+	stats := httputil.MediaCacheStats()
+
+	p.Stats.SetText(fmt.Sprintf(
+		"Media cache: %.1f MiB in %d files\nHit rate since startup: %.0f%% of %d requests",
+		float64(stats.Size)/(1<<20), stats.Entries,
+		stats.HitRate()*100, stats.Hits+stats.Misses,
+	))
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/gotk3/gotk3/pango"
+)
+
+type _strlist struct {
+	value    *[]string
+	validate func(string) error
+	change   func([]string)
+}
+
+// StringList creates an editable list of unique strings. Validate, if not nil,
+// is called on every item before it is added.
+func StringList(value *[]string, validate func(string) error, change func([]string)) EntryValue {
+	return &_strlist{value, validate, change}
+}
+
+func (l *_strlist) check(item string) error {
+	if item == "" {
+		return fmt.Errorf("item must not be empty")
+	}
+	if l.validate != nil {
+		if err := l.validate(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *_strlist) set(v []string) error {
+	var seen = make(map[string]struct{}, len(v))
+
+	for _, item := range v {
+		if err := l.check(item); err != nil {
+			return err
+		}
+		if _, dup := seen[item]; dup {
+			return fmt.Errorf("duplicate item %q", item)
+		}
+		seen[item] = struct{}{}
+	}
+
+	*l.value = v
+	if l.change != nil {
+		l.change(v)
+	}
+	return nil
+}
+
+func (l *_strlist) add(item string) error {
+	var v = make([]string, 0, len(*l.value)+1)
+	v = append(v, *l.value...)
+	v = append(v, item)
+	return l.set(v)
+}
+
+func (l *_strlist) remove(item string) {
+	var v = make([]string, 0, len(*l.value))
+	for _, it := range *l.value {
+		if it != item {
+			v = append(v, it)
+		}
+	}
+	l.set(v)
+}
+
+func (l *_strlist) Construct() gtk.IWidget {
+	list, _ := gtk.ListBoxNew()
+	list.SetSelectionMode(gtk.SELECTION_NONE)
+	list.Show()
+	primitives.AddClass(list, "frame")
+
+	addRow := func(item string) {
+		label, _ := gtk.LabelNew(item)
+		label.SetXAlign(0)
+		label.SetHExpand(true)
+		label.SetEllipsize(pango.ELLIPSIZE_MIDDLE)
+		label.Show()
+
+		remove, _ := gtk.ButtonNewFromIconName("list-remove-symbolic", gtk.ICON_SIZE_BUTTON)
+		remove.SetRelief(gtk.RELIEF_NONE)
+		remove.Show()
+
+		box, _ := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 4)
+		box.PackStart(label, true, true, 0)
+		box.PackStart(remove, false, false, 0)
+		box.Show()
+
+		row, _ := gtk.ListBoxRowNew()
+		row.Add(box)
+		row.Show()
+
+		remove.Connect("clicked", func(*gtk.Button) {
+			l.remove(item)
+			list.Remove(row)
+		})
+
+		list.Add(row)
+	}
+
+	for _, item := range *l.value {
+		addRow(item)
+	}
+
+	entry, _ := gtk.EntryNew()
+	entry.SetPlaceholderText("Add...")
+	entry.SetIconFromIconName(gtk.ENTRY_ICON_SECONDARY, "list-add-symbolic")
+	entry.Show()
+
+	box, _ := gtk.BoxNew(gtk.ORIENTATION_VERTICAL, 2)
+	box.SetSizeRequest(200, -1)
+	box.PackStart(list, false, false, 0)
+	box.PackStart(entry, false, false, 0)
+	box.Show()
+
+	fb := newFeedback(box)
+
+	submit := func() {
+		text, _ := entry.GetText()
+		text = strings.TrimSpace(text)
+
+		if err := l.add(text); err != nil {
+			fb.set(err)
+			return
+		}
+
+		fb.set(nil)
+		addRow(text)
+		entry.SetText("")
+	}
+
+	entry.Connect("activate", func(*gtk.Entry) { submit() })
+	entry.Connect("icon-press", func(*gtk.Entry) { submit() })
+
+	return fb
+}
+
+func (l *_strlist) MarshalJSON() ([]byte, error) {
+	return json.Marshal(*l.value)
+}
+
+func (l *_strlist) UnmarshalJSON(b []byte) error {
+	var value []string
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	return l.set(value)
+}
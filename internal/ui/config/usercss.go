@@ -0,0 +1,26 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/diamondburned/cchat-gtk/internal/gts"
+	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/pkg/errors"
+)
+
+// UserCSSFile is the optional stylesheet in the config directory that is loaded
+// on top of the built-in styles. It is reloaded when it changes.
+const UserCSSFile = "user.css"
+
+func loadUserCSS() {
+	b, err := ioutil.ReadFile(filepath.Join(DirPath(), UserCSSFile))
+	if err != nil && !os.IsNotExist(err) {
+		log.Error(errors.Wrap(err, "Failed to read "+UserCSSFile))
+		return
+	}
+
+	// A missing file unloads the previous user CSS.
+	gts.LoadUserCSS(UserCSSFile, string(b))
+}
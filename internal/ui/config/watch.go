@@ -0,0 +1,198 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/cchat-gtk/internal/gts"
+	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/pkg/errors"
+)
+
+// WatchInterval is the interval at which the config directory is polled for
+// changes. Polling is used over inotify, since the directory only contains a
+// handful of small files and this works everywhere.
+const WatchInterval = time.Second
+
+// fileStamp is the state of a file to compare against when polling.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+var watcher = struct {
+	sync.Mutex
+	stamps   map[string]fileStamp // relative path -> stamp
+	handlers map[string]*Updaters // relative path -> handlers
+	started  bool
+}{
+	stamps:   map[string]fileStamp{},
+	handlers: map[string]*Updaters{},
+}
+
+// OnFileChange adds a callback that is called in the main thread when the given
+// file, which is relative to the config directory, is changed on disk by
+// something other than cchat-gtk. The callback is also called if the file is
+// deleted. Nothing is called until Watch is called.
+func OnFileChange(file string, f func()) {
+	watcher.Lock()
+	defer watcher.Unlock()
+
+	onFileChange(file, f)
+}
+
+func onFileChange(file string, f func()) {
+	file = filepath.Clean(file)
+
+	us, ok := watcher.handlers[file]
+	if !ok {
+		us = &Updaters{}
+		watcher.handlers[file] = us
+	}
+
+	us.Add(f)
+}
+
+// Watch starts watching the config directory for changes in the background.
+// The main config, configs given to RegisterConfig and user.css are reloaded
+// when they change. Calling Watch more than once does nothing.
+func Watch() {
+	watcher.Lock()
+	defer watcher.Unlock()
+
+	if watcher.started {
+		return
+	}
+	watcher.started = true
+
+	onFileChange(ConfigFile, func() { restoreMain(true) })
+	onFileChange(UserCSSFile, loadUserCSS)
+
+	for file, v := range toRestore {
+		file, v := file, v
+		onFileChange(file, func() {
+			// Start over, so that the values removed from the file are
+			// removed as well.
+			resetValue(v)
+
+			if err := UnmarshalFromFile(file, v); err != nil {
+				log.Error(errors.Wrapf(err, "Failed to reload %s", file))
+			}
+
+			if us, ok := reloaders[file]; ok {
+				us.Updated()
+			}
+		})
+	}
+
+	// Take the initial snapshot synchronously, so only changes after this
+	// call are picked up.
+	watcher.stamps = scanDir(DirPath())
+
+	loadUserCSS()
+
+	go func() {
+		for range time.Tick(WatchInterval) {
+			poll()
+		}
+	}()
+}
+
+func poll() {
+	// Scan with the lock acquired, so that writes by cchat-gtk can't change
+	// the stamps in between.
+	watcher.Lock()
+
+	stamps := scanDir(DirPath())
+
+	var changed Updaters
+
+	for file, us := range watcher.handlers {
+		old, hadOld := watcher.stamps[file]
+		now, hasNow := stamps[file]
+
+		if hadOld != hasNow || old != now {
+			changed = append(changed, *us...)
+		}
+	}
+
+	watcher.stamps = stamps
+	watcher.Unlock()
+
+	if len(changed) == 0 {
+		return
+	}
+
+	gts.ExecAsync(changed.Updated)
+}
+
+// replaceWritten renames the temporary file over the given absolute path and
+// updates its stamp, so that writes made by cchat-gtk itself are not treated
+// as external changes. Both are done with the lock acquired, so that a poll
+// can't see the new file with the old stamp.
+func replaceWritten(tmp, path string) error {
+	watcher.Lock()
+	defer watcher.Unlock()
+
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(DirPath(), path)
+	if err != nil {
+		return nil
+	}
+
+	if s, err := os.Stat(path); err == nil {
+		watcher.stamps[rel] = fileStamp{s.ModTime(), s.Size()}
+	}
+
+	return nil
+}
+
+// resetValue sets the value that the pointer points to to its zero value.
+// Maps are cleared instead, so that they can still be written to.
+func resetValue(ptr interface{}) {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return
+	}
+
+	v = v.Elem()
+
+	if v.Kind() == reflect.Map && !v.IsNil() {
+		for _, key := range v.MapKeys() {
+			v.SetMapIndex(key, reflect.Value{})
+		}
+		return
+	}
+
+	v.Set(reflect.Zero(v.Type()))
+}
+
+func scanDir(dir string) map[string]fileStamp {
+	var stamps = map[string]fileStamp{}
+
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+
+		// Skip backups and temporary files.
+		if strings.HasPrefix(info.Name(), ".") || strings.HasSuffix(path, BackupSuffix) {
+			return nil
+		}
+
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			stamps[rel] = fileStamp{info.ModTime(), info.Size()}
+		}
+
+		return nil
+	})
+
+	return stamps
+}
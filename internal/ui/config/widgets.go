@@ -3,7 +3,9 @@ package config
 import (
 	"encoding/json"
 
+	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
 	"github.com/gotk3/gotk3/gtk"
+	"github.com/gotk3/gotk3/pango"
 )
 
 // EntryValue with JSON serde capabilities.
@@ -13,6 +15,68 @@ type EntryValue interface {
 	Construct() gtk.IWidget
 }
 
+// Describer is an optional interface that an EntryValue can implement to have
+// a description shown under its name and as its tooltip.
+type Describer interface {
+	Description() string
+}
+
+type _described struct {
+	EntryValue
+	desc string
+}
+
+// Describe wraps the entry value to add a description.
+func Describe(desc string, value EntryValue) EntryValue {
+	return _described{value, desc}
+}
+
+func (d _described) Description() string {
+	return d.desc
+}
+
+var feedbackCSS = primitives.PrepareClassCSS("config-feedback", `
+	.config-feedback {
+		color: @error_color;
+		font-size: 0.85em;
+	}
+`)
+
+// feedback wraps an entry's widget to show validation errors inline right
+// under it.
+type feedback struct {
+	*gtk.Box
+	label *gtk.Label
+}
+
+func newFeedback(child gtk.IWidget) *feedback {
+	label, _ := gtk.LabelNew("")
+	label.SetXAlign(1)
+	label.SetLineWrap(true)
+	label.SetLineWrapMode(pango.WRAP_WORD_CHAR)
+	feedbackCSS(label)
+
+	box, _ := gtk.BoxNew(gtk.ORIENTATION_VERTICAL, 2)
+	box.SetHAlign(gtk.ALIGN_END)
+	box.SetVAlign(gtk.ALIGN_CENTER)
+	box.PackStart(child, false, false, 0)
+	box.PackStart(label, false, false, 0)
+	box.Show()
+
+	return &feedback{box, label}
+}
+
+// set shows the error, or hides the previous one if err is nil.
+func (f *feedback) set(err error) {
+	if err == nil {
+		f.label.Hide()
+		return
+	}
+
+	f.label.SetText(err.Error())
+	f.label.Show()
+}
+
 type _combo struct {
 	selected *int
 	options  []string
@@ -53,7 +117,7 @@ func (c *_combo) UnmarshalJSON(b []byte) error {
 	if err := json.Unmarshal(b, &value); err != nil {
 		return err
 	}
-	*c.selected = value
+	c.set(value)
 	return nil
 }
 
@@ -99,10 +163,16 @@ func (s *_switch) UnmarshalJSON(b []byte) error {
 type _inputentry struct {
 	value  *string
 	change func(string) error
+	hidden bool
 }
 
 func InputEntry(value *string, change func(string) error) EntryValue {
-	return &_inputentry{value, change}
+	return &_inputentry{value, change, false}
+}
+
+// PasswordEntry is an InputEntry that hides its text.
+func PasswordEntry(value *string, change func(string) error) EntryValue {
+	return &_inputentry{value, change, true}
 }
 
 func (e *_inputentry) set(v string) error {
@@ -118,6 +188,11 @@ func (e *_inputentry) Construct() gtk.IWidget {
 	entry.SetHExpand(true)
 	entry.SetText(*e.value)
 
+	if e.hidden {
+		entry.SetVisibility(false)
+		entry.SetInputPurpose(gtk.INPUT_PURPOSE_PASSWORD)
+	}
+
 	entry.Connect("changed", func(entry *gtk.Entry) {
 		v, err := entry.GetText()
 		if err != nil {
@@ -0,0 +1,222 @@
+package credentials
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-gtk/internal/gts"
+	"github.com/diamondburned/cchat-gtk/internal/keyring"
+	"github.com/diamondburned/cchat-gtk/internal/keyring/driver"
+	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
+	"github.com/diamondburned/cchat-gtk/internal/ui/rich"
+	"github.com/diamondburned/cchat/services"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/gotk3/gotk3/pango"
+	"github.com/pkg/errors"
+)
+
+// Page is the preferences page that shows which provider holds each service's
+// sessions and allows migrating them between providers.
+type Page struct {
+	*gtk.Box
+	Grid     *gtk.Grid
+	From     *gtk.ComboBoxText
+	To       *gtk.ComboBoxText
+	Migrate  *gtk.Button
+	ErrLabel *gtk.Label
+
+	providers []driver.Provider
+	services  []cchat.Service
+	names     []*rich.NameContainer
+	locations []*gtk.Label
+}
+
+// NewPage creates a new credentials page. The locations are loaded in the
+// background.
+func NewPage() *Page {
+	p := &Page{
+		providers: keyring.Providers(),
+	}
+	p.services, _ = services.Get()
+
+	p.Grid, _ = gtk.GridNew()
+	p.Grid.SetRowSpacing(4)
+	p.Grid.SetColumnSpacing(8)
+	p.Grid.Show()
+	primitives.AddClass(p.Grid, "config")
+
+	for i, svc := range p.services {
+		name, _ := gtk.LabelNew(svc.ID())
+		name.SetHExpand(true)
+		name.SetXAlign(0)
+		name.SetEllipsize(pango.ELLIPSIZE_END)
+		name.Show()
+
+		namec := &rich.NameContainer{}
+		namec.OnUpdate(func() { name.SetText(namec.String()) })
+		namec.BindNamer(name, "destroy", svc)
+
+		location, _ := gtk.LabelNew("Loading...")
+		location.SetXAlign(1)
+		location.SetLineWrap(true)
+		location.SetLineWrapMode(pango.WRAP_WORD_CHAR)
+		location.Show()
+
+		p.Grid.Attach(name, 0, i, 1, 1)
+		p.Grid.Attach(location, 1, i, 1, 1)
+
+		p.names = append(p.names, namec)
+		p.locations = append(p.locations, location)
+	}
+
+	p.From = p.newProviderCombo(len(p.providers) - 1) // plaintext fallback
+	p.To = p.newProviderCombo(0)                      // system keyring
+
+	arrow, _ := gtk.LabelNew("→")
+	arrow.Show()
+
+	p.Migrate, _ = gtk.ButtonNewWithLabel("Migrate")
+	p.Migrate.SetTooltipText("Move all sessions and delete the old copies")
+	p.Migrate.Connect("clicked", func(*gtk.Button) { p.migrateAll() })
+	p.Migrate.Show()
+
+	migrate, _ := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 4)
+	migrate.PackStart(p.From, true, true, 0)
+	migrate.PackStart(arrow, false, false, 0)
+	migrate.PackStart(p.To, true, true, 0)
+	migrate.PackStart(p.Migrate, false, false, 0)
+	migrate.Show()
+
+	p.ErrLabel, _ = gtk.LabelNew("")
+	p.ErrLabel.SetXAlign(0)
+	p.ErrLabel.SetLineWrap(true)
+	p.ErrLabel.SetLineWrapMode(pango.WRAP_WORD_CHAR)
+
+	p.Box, _ = gtk.BoxNew(gtk.ORIENTATION_VERTICAL, 8)
+	p.Box.PackStart(p.Grid, false, false, 0)
+	p.Box.PackStart(migrate, false, false, 0)
+	p.Box.PackStart(p.ErrLabel, false, false, 0)
+	p.Box.Show()
+
+	p.Refresh()
+
+	return p
+}
+
+func (p *Page) newProviderCombo(active int) *gtk.ComboBoxText {
+	combo, _ := gtk.ComboBoxTextNew()
+	for _, provider := range p.providers {
+		combo.Append(provider.Name(), provider.Name())
+	}
+	combo.SetActive(active)
+	combo.Show()
+
+	return combo
+}
+
+// Refresh reloads the locations of all services in the background.
+func (p *Page) Refresh() {
+	services := p.services
+
+	gts.Async(func() (func(), error) {
+		var holders = make([][]driver.Provider, len(services))
+		for i, svc := range services {
+			holders[i] = keyring.Locate(svc.ID())
+		}
+
+		return func() {
+			for i, location := range p.locations {
+				location.SetText(describeHolders(holders[i]))
+			}
+		}, nil
+	})
+}
+
+func (p *Page) migrateAll() {
+	from := p.providers[p.From.GetActive()]
+	to := p.providers[p.To.GetActive()]
+
+	if from == to {
+		setError(p.ErrLabel, "Source and destination are the same.")
+		return
+	}
+
+	p.ErrLabel.Hide()
+	p.SetSensitive(false)
+
+	services := p.services
+
+	gts.Async(func() (func(), error) {
+		var errs []string
+		var skipped []int
+
+		for i, svc := range services {
+			ok, err := from.Contains(svc.ID())
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			if !ok {
+				continue
+			}
+
+			switch err := keyring.Migrate(svc.ID(), from, to); err {
+			case nil:
+			case driver.ErrExists:
+				skipped = append(skipped, i)
+			default:
+				errs = append(errs, errors.Wrap(err, svc.ID()).Error())
+			}
+		}
+
+		return func() {
+			p.SetSensitive(true)
+			p.Refresh()
+
+			var notice string
+			if len(skipped) > 0 {
+				var names = make([]string, len(skipped))
+				for i, j := range skipped {
+					if names[i] = p.names[j].String(); names[i] == "" {
+						names[i] = services[j].ID()
+					}
+				}
+
+				notice = fmt.Sprintf(
+					"Skipped %s, since %s already has their sessions. "+
+						"Both copies were kept.",
+					strings.Join(names, ", "), to.Name(),
+				)
+			}
+
+			switch {
+			case len(errs) > 0:
+				if notice != "" {
+					errs = append(errs, notice)
+				}
+				setError(p.ErrLabel, strings.Join(errs, "\n"))
+			case notice != "":
+				p.ErrLabel.SetText(notice)
+				p.ErrLabel.Show()
+			}
+		}, nil
+	})
+}
+
+func describeHolders(holders []driver.Provider) string {
+	if len(holders) == 0 {
+		return "Not saved"
+	}
+
+	if len(holders) == 1 {
+		return holders[0].Name()
+	}
+
+	var stale = make([]string, len(holders)-1)
+	for i, holder := range holders[1:] {
+		stale[i] = holder.Name()
+	}
+
+	return holders[0].Name() + " (stale copy in " + strings.Join(stale, ", ") + ")"
+}
@@ -0,0 +1,105 @@
+// Package credentials contains widgets for managing where session secrets are
+// stored.
+package credentials
+
+import (
+	"html"
+
+	"github.com/diamondburned/cchat-gtk/internal/gts"
+	"github.com/diamondburned/cchat-gtk/internal/keyring"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/gotk3/gotk3/pango"
+)
+
+// PromptPassphrase prompts the user for the passphrase of the encrypted
+// keyring if needed. This function blocks until the dialog is closed, so it
+// must be called before any session is restored. If the user skips the prompt,
+// then secrets will be stored in plaintext instead.
+func PromptPassphrase() {
+	if !keyring.NeedsPassphrase() {
+		return
+	}
+
+	// If there are no encrypted secrets yet, then the user is setting a new
+	// passphrase, so ask for confirmation.
+	var creating = !keyring.HasEncryptedSecrets()
+
+	var description string
+	if creating {
+		description = "The system keyring is unavailable. " +
+			"Set a passphrase to encrypt saved sessions, " +
+			"or skip to store them in plaintext."
+	} else {
+		description = "Enter the passphrase to unlock saved sessions."
+	}
+
+	d, _ := gts.NewModalDialog()
+	d.SetTitle("Unlock Sessions")
+	d.SetDefaultSize(350, -1)
+	d.AddButton("_Skip", gtk.RESPONSE_CANCEL)
+	d.AddButton("_Unlock", gtk.RESPONSE_ACCEPT)
+	d.SetDefaultResponse(gtk.RESPONSE_ACCEPT)
+
+	desc, _ := gtk.LabelNew(description)
+	desc.SetXAlign(0)
+	desc.SetLineWrap(true)
+	desc.SetLineWrapMode(pango.WRAP_WORD_CHAR)
+	desc.Show()
+
+	pass := newPasswordEntry("Passphrase")
+	pass.Show()
+
+	confirm := newPasswordEntry("Confirm passphrase")
+	confirm.SetVisible(creating)
+
+	errLabel, _ := gtk.LabelNew("")
+	errLabel.SetXAlign(0)
+	errLabel.SetLineWrap(true)
+	errLabel.SetLineWrapMode(pango.WRAP_WORD_CHAR)
+
+	box, _ := d.GetContentArea()
+	box.SetSpacing(8)
+	box.SetMarginTop(8)
+	box.SetMarginBottom(8)
+	box.SetMarginStart(16)
+	box.SetMarginEnd(16)
+	box.PackStart(desc, false, false, 0)
+	box.PackStart(pass, false, false, 0)
+	box.PackStart(confirm, false, false, 0)
+	box.PackStart(errLabel, false, false, 0)
+
+	defer d.Destroy()
+
+	for d.Run() == gtk.RESPONSE_ACCEPT {
+		passphrase, _ := pass.GetText()
+
+		if creating {
+			if confirmation, _ := confirm.GetText(); confirmation != passphrase {
+				setError(errLabel, "Passphrases do not match.")
+				continue
+			}
+		}
+
+		if err := keyring.Unlock(passphrase); err != nil {
+			setError(errLabel, err.Error())
+			pass.GrabFocus()
+			continue
+		}
+
+		return
+	}
+}
+
+func newPasswordEntry(placeholder string) *gtk.Entry {
+	e, _ := gtk.EntryNew()
+	e.SetVisibility(false)
+	e.SetInputPurpose(gtk.INPUT_PURPOSE_PASSWORD)
+	e.SetPlaceholderText(placeholder)
+	e.SetActivatesDefault(true)
+	return e
+}
+
+func setError(l *gtk.Label, msg string) {
+	l.SetMarkup(`<span color="red">Error:</span> ` + html.EscapeString(msg))
+	l.Show()
+}
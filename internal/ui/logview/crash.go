@@ -0,0 +1,51 @@
+package logview
+
+import (
+	"github.com/diamondburned/cchat-gtk/internal/crash"
+	"github.com/diamondburned/cchat-gtk/internal/gts"
+	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/pkg/errors"
+	"github.com/skratchdot/open-golang/open"
+)
+
+const showReportResponse gtk.ResponseType = 1
+
+// PromptCrashReports asks the user to look at the crash reports written since
+// the last launch, if there are any. The reports are marked as seen either way.
+func PromptCrashReports() {
+	reports := crash.UnseenReports()
+	if len(reports) == 0 {
+		return
+	}
+
+	latest := reports[len(reports)-1]
+
+	d := gtk.MessageDialogNew(
+		gts.App.Window, gtk.DIALOG_MODAL|gtk.DIALOG_DESTROY_WITH_PARENT,
+		gtk.MESSAGE_WARNING, gtk.BUTTONS_NONE,
+		"cchat-gtk crashed during the last session.",
+	)
+	d.FormatSecondaryText("A crash report was saved to %s.", latest)
+	d.AddButton("_Dismiss", gtk.RESPONSE_CLOSE)
+	d.AddButton("_Show Report", showReportResponse)
+	d.SetDefaultResponse(showReportResponse)
+
+	d.Connect("response", func(_ interface{}, resp gtk.ResponseType) {
+		d.Destroy()
+
+		if resp == showReportResponse {
+			if err := open.Start(latest); err != nil {
+				log.Error(errors.Wrap(err, "Failed to open crash report"))
+			}
+		}
+
+		for _, report := range reports {
+			if err := crash.MarkSeen(report); err != nil {
+				log.Error(errors.Wrap(err, "Failed to mark crash report as seen"))
+			}
+		}
+	})
+
+	d.Show()
+}
@@ -0,0 +1,27 @@
+package logview
+
+import (
+	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/diamondburned/cchat-gtk/internal/profile"
+	"github.com/diamondburned/cchat-gtk/internal/ui/config"
+	"github.com/pkg/errors"
+)
+
+var writeLogFile = false
+
+func init() {
+	config.Register(config.Privacy, "Write Log File", config.Describe(
+		"Keep a rotating log file in "+profile.CacheDir()+". Logs may contain "+
+			"server names and error messages.",
+		config.Switch(&writeLogFile, func(b bool) {
+			if !b {
+				log.CloseFile()
+				return
+			}
+
+			if err := log.OpenFile(profile.CacheDir()); err != nil {
+				log.Error(errors.Wrap(err, "Failed to open log file"))
+			}
+		}),
+	))
+}
@@ -0,0 +1,182 @@
+// Package logview provides a window that shows the log buffer live.
+package logview
+
+import (
+	"strings"
+
+	"github.com/diamondburned/cchat-gtk/internal/gts"
+	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
+	"github.com/gotk3/gotk3/gtk"
+)
+
+// Window is the log viewer window.
+type Window struct {
+	*gtk.Window
+	Header *gtk.HeaderBar
+	Search *gtk.SearchEntry
+	Level  *gtk.ComboBoxText
+	Copy   *gtk.Button
+
+	View   *gtk.TextView
+	buffer *gtk.TextBuffer
+	tags   [3]*gtk.TextTag // indexed by log.Level
+
+	entries []log.Entry // shown and hidden, bounded by log.BufferSize
+	filter  string
+	level   log.Level
+}
+
+// current is the currently opened window, if any.
+var current *Window
+
+var textCSS = primitives.PrepareClassCSS("log-view", `
+	.log-view {
+		font-family: monospace;
+		padding: 4px 8px;
+	}
+`)
+
+// Spawn shows the log window, or presents it if it's already opened.
+func Spawn() {
+	if current != nil {
+		current.Present()
+		return
+	}
+
+	current = New()
+	current.Connect("destroy", func(interface{}) { current = nil })
+	current.Show()
+}
+
+// New creates a new log window that updates itself until it's destroyed.
+func New() *Window {
+	w := &Window{}
+
+	w.View, _ = gtk.TextViewNew()
+	w.View.SetEditable(false)
+	w.View.SetCursorVisible(false)
+	w.View.SetWrapMode(gtk.WRAP_WORD_CHAR)
+	w.View.Show()
+	textCSS(w.View)
+
+	w.buffer, _ = w.View.GetBuffer()
+	w.tags[log.LevelInfo] = w.buffer.CreateTag("info", map[string]interface{}{})
+	w.tags[log.LevelWarn] = w.buffer.CreateTag("warn", map[string]interface{}{
+		"foreground": "#d08700",
+	})
+	w.tags[log.LevelError] = w.buffer.CreateTag("error", map[string]interface{}{
+		"foreground": "#e01b24",
+		"weight":     700,
+	})
+
+	sw, _ := gtk.ScrolledWindowNew(nil, nil)
+	sw.SetPolicy(gtk.POLICY_AUTOMATIC, gtk.POLICY_AUTOMATIC)
+	sw.Add(w.View)
+	sw.Show()
+
+	w.Search, _ = gtk.SearchEntryNew()
+	w.Search.SetPlaceholderText("Filter")
+	w.Search.Connect("search-changed", func(s *gtk.SearchEntry) {
+		w.filter, _ = s.GetText()
+		w.filter = strings.ToLower(w.filter)
+		w.render()
+	})
+	w.Search.Show()
+
+	w.Level, _ = gtk.ComboBoxTextNew()
+	w.Level.Append("info", "All")
+	w.Level.Append("warn", "Warnings")
+	w.Level.Append("error", "Errors")
+	w.Level.SetActive(int(log.LevelInfo))
+	w.Level.Connect("changed", func(c *gtk.ComboBoxText) {
+		w.level = log.Level(c.GetActive())
+		w.render()
+	})
+	w.Level.Show()
+
+	w.Copy, _ = gtk.ButtonNewFromIconName("edit-copy-symbolic", gtk.ICON_SIZE_BUTTON)
+	w.Copy.SetTooltipText("Copy shown entries")
+	w.Copy.Connect("clicked", func(*gtk.Button) {
+		start, end := w.buffer.GetBounds()
+		text, _ := w.buffer.GetText(start, end, false)
+		gts.Clipboard.SetText(text)
+	})
+	w.Copy.Show()
+
+	w.Header, _ = gtk.HeaderBarNew()
+	w.Header.SetTitle("Logs")
+	w.Header.SetShowCloseButton(true)
+	w.Header.PackStart(w.Search)
+	w.Header.PackStart(w.Level)
+	w.Header.PackEnd(w.Copy)
+	w.Header.Show()
+
+	w.Window, _ = gtk.WindowNew(gtk.WINDOW_TOPLEVEL)
+	w.Window.SetTitlebar(w.Header)
+	w.Window.SetDefaultSize(700, 400)
+	w.Window.Add(sw)
+	gts.AddWindow(w.Window)
+
+	entries, remove := log.SubscribeEntries(func(entry log.Entry) {
+		gts.ExecAsync(func() { w.add(entry) })
+	})
+
+	w.entries = entries
+	w.render()
+	w.Window.Connect("destroy", func(interface{}) { remove() })
+
+	return w
+}
+
+func (w *Window) matches(entry log.Entry) bool {
+	if entry.Level < w.level {
+		return false
+	}
+	if w.filter != "" && !strings.Contains(strings.ToLower(entry.Msg), w.filter) {
+		return false
+	}
+	return true
+}
+
+// render rewrites the whole buffer with the entries that match the filter.
+func (w *Window) render() {
+	w.buffer.SetText("")
+
+	for _, entry := range w.entries {
+		if w.matches(entry) {
+			w.insert(entry)
+		}
+	}
+}
+
+func (w *Window) add(entry log.Entry) {
+	// Keep the same bound as the log buffer.
+	if len(w.entries) >= log.BufferSize {
+		// The oldest entry is at the start if it's shown. It may span
+		// multiple lines.
+		if oldest := w.entries[0]; w.matches(oldest) {
+			start := w.buffer.GetStartIter()
+			end := w.buffer.GetIterAtLine(entryLines(oldest))
+			w.buffer.Delete(start, end)
+		}
+
+		w.entries = append(w.entries[:0], w.entries[1:]...)
+	}
+
+	w.entries = append(w.entries, entry)
+
+	if w.matches(entry) {
+		w.insert(entry)
+	}
+}
+
+// entryLines returns the number of buffer lines that the inserted entry spans.
+func entryLines(entry log.Entry) int {
+	return strings.Count(entry.String(), "\n") + 1
+}
+
+func (w *Window) insert(entry log.Entry) {
+	end := w.buffer.GetEndIter()
+	w.buffer.InsertWithTag(end, entry.String()+"\n", w.tags[entry.Level])
+}
@@ -50,6 +50,7 @@ var _ container.MessageRow = (*Message)(nil)
 
 var renderCfg = markup.RenderConfig{
 	NoReferencing: true,
+	NoLinkify:     true,
 }
 
 func WrapMessage(ct *message.State) Message {
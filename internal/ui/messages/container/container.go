@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-gtk/internal/ui/config"
 	"github.com/diamondburned/cchat-gtk/internal/ui/messages/message"
 	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
 	"github.com/diamondburned/handy"
@@ -12,7 +13,14 @@ import (
 
 // BacklogLimit is the maximum number of messages to store in the container at
 // once.
-const BacklogLimit = 50
+var BacklogLimit = 50
+
+func init() {
+	config.Register(config.Behavior, "Backlog Size", config.Describe(
+		"The number of messages kept in a channel before older ones are cleaned up.",
+		config.Spin(&BacklogLimit, 20, 1000, nil),
+	))
+}
 
 type MessageRow interface {
 	message.Container
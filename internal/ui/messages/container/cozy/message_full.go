@@ -56,6 +56,7 @@ func NewFullMessage(msg cchat.MessageCreate) *FullMessage {
 
 var renderCfg = markup.RenderConfig{
 	NoReferencing: true,
+	NoLinkify:     true,
 }
 
 func WrapFullMessage(gc *message.State) *FullMessage {
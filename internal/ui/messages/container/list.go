@@ -1,11 +1,13 @@
 package container
 
 import (
+	"fmt"
 	"log"
 	"strings"
 	"time"
 
 	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-gtk/internal/crash"
 	"github.com/diamondburned/cchat-gtk/internal/gts"
 	"github.com/diamondburned/cchat-gtk/internal/ui/messages/message"
 	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
@@ -38,7 +40,7 @@ func newKey(state *message.State) messageKey {
 func parseKeyFromNamer(n primitives.Namer) messageKey {
 	name, err := n.GetName()
 	if err != nil {
-		panic("BUG: failed to get primitive name: " + err.Error())
+		panic(crash.Reported("BUG: failed to get primitive name: " + err.Error()))
 	}
 
 	parts := strings.SplitN(name, ":", 2)
@@ -52,7 +54,7 @@ func parseKeyFromNamer(n primitives.Namer) messageKey {
 	case "nonce":
 		return messageKey{id: parts[1], nonce: true}
 	default:
-		panic("unknown prefix in message row name " + parts[0])
+		panic(crash.Reported("unknown prefix in message row name " + parts[0]))
 	}
 }
 
@@ -451,7 +453,7 @@ func (c *ListStore) DeleteEarliest(n int) {
 
 		mr, ok := c.messages[id]
 		if !ok {
-			log.Panicln("message with ID", id, "not found in map")
+			panic(crash.Reported(fmt.Sprint("message with ID ", id, " not found in map")))
 		}
 
 		delete(c.messages, id)
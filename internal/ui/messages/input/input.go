@@ -6,6 +6,7 @@ import (
 	"github.com/diamondburned/cchat"
 	"github.com/diamondburned/cchat-gtk/internal/gts"
 	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/diamondburned/cchat-gtk/internal/ui/config"
 	"github.com/diamondburned/cchat-gtk/internal/ui/messages/container"
 	"github.com/diamondburned/cchat-gtk/internal/ui/messages/input/attachment"
 	"github.com/diamondburned/cchat-gtk/internal/ui/messages/input/username"
@@ -111,6 +112,16 @@ func (v *InputView) SetMessenger(session cchat.Session, messenger cchat.Messenge
 	v.Completer.SetCompleter(completer)
 }
 
+// announceTyping controls whether or not typing indications are sent.
+var announceTyping = true
+
+func init() {
+	config.Register(config.Behavior, "Announce Typing", config.Describe(
+		"Let others know when you are typing, if the service supports it.",
+		config.Switch(&announceTyping, nil),
+	))
+}
+
 // wrapSpellCheck is a no-op but is replaced by gspell in ./spellcheck.go.
 var wrapSpellCheck = func(textView *gtk.TextView) {}
 
@@ -102,7 +102,7 @@ func (f *Field) keyDown(tv *gtk.TextView, ev *gdk.Event) bool {
 
 	// If the server supports typing indication, then announce that we are
 	// typing with a proper rate limit.
-	if f.typing != nil {
+	if f.typing != nil && announceTyping {
 		// Get the current time; if the next timestamp is before now, then that
 		// means it's time for us to update it and send a typing indication.
 		if now := time.Now(); f.lastTyped.Add(f.typerDura).Before(now) {
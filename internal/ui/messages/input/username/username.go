@@ -21,9 +21,9 @@ var (
 
 func init() {
 	// Bind this revealer in settings.
-	config.AppearanceAdd("Show Username in Input", config.Switch(
-		&showUser,
-		func(b bool) { updaters.Updated() },
+	config.AppearanceAdd("Show Username in Input", config.Describe(
+		"Show your avatar and name next to the message input.",
+		config.Switch(&showUser, func(b bool) { updaters.Updated() }),
 	))
 }
 
@@ -0,0 +1,146 @@
+package linkpreview
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/diamondburned/cchat-gtk/internal/gts/httputil"
+	"github.com/diamondburned/cchat-gtk/internal/offline"
+	"github.com/pkg/errors"
+)
+
+// client fetches the linked pages. Links are posted by other users, so it
+// refuses to connect to local and private addresses, which would let them make
+// requests into the local network. Every redirect goes through the transport,
+// so they're checked as well.
+var client = http.Client{
+	Timeout:   15 * time.Second,
+	Transport: publicTransport{},
+}
+
+var (
+	// directTransport checks the addresses it connects to again, in case the
+	// host resolves to a different address the second time.
+	directTransport = &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 15 * time.Second,
+			Control: dialPublic,
+		}).DialContext,
+	}
+	// proxiedTransport connects to the proxy, which may be local, and leaves
+	// resolving the host to it.
+	proxiedTransport = &http.Transport{
+		Proxy: httputil.Proxy,
+	}
+)
+
+type publicTransport struct{}
+
+func (publicTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if err := checkPublic(r.Context(), r.URL.Hostname()); err != nil {
+		return nil, err
+	}
+
+	proxy, err := httputil.Proxy(r)
+	if err != nil {
+		return nil, err
+	}
+	if proxy != nil {
+		return proxiedTransport.RoundTrip(r)
+	}
+
+	return directTransport.RoundTrip(r)
+}
+
+// get fetches the URL with the client. Nothing is fetched while offline.
+func get(ctx context.Context, link string) (*http.Response, error) {
+	if offline.IsOffline() {
+		return nil, httputil.ErrOffline
+	}
+
+	q, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make a request")
+	}
+
+	r, err := client.Do(q)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		r.Body.Close()
+		return nil, errors.Errorf("unexpected status %d", r.StatusCode)
+	}
+
+	return r, nil
+}
+
+// checkPublic returns an error if the host resolves to any address that isn't
+// public.
+func checkPublic(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return errors.Wrap(err, "failed to resolve host")
+	}
+
+	for _, addr := range addrs {
+		if !isPublic(addr.IP) {
+			return errors.Errorf("refusing to connect to %s at %s", host, addr.IP)
+		}
+	}
+
+	return nil
+}
+
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+		return errors.Errorf("refusing to connect to %s", host)
+	}
+
+	return nil
+}
+
+// privateNets are the address ranges that aren't reachable from the internet
+// and aren't covered by the net.IP methods.
+var privateNets = parseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"fc00::/7",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	var nets = make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, nets[i], _ = net.ParseCIDR(cidr)
+	}
+	return nets
+}
+
+// isPublic returns true if the IP is a public unicast address.
+func isPublic(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+
+	for _, n := range privateNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,30 @@
+package linkpreview
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	var tests = map[string]bool{
+		"1.1.1.1":          true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"0.0.0.0":          false,
+		"10.1.2.3":         false,
+		"172.20.0.1":       false,
+		"192.168.1.1":      false,
+		"100.64.0.1":       false,
+		"169.254.169.254":  false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"::ffff:127.0.0.1": false,
+	}
+
+	for ip, public := range tests {
+		if isPublic(net.ParseIP(ip)) != public {
+			t.Errorf("isPublic(%s) != %v", ip, public)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package linkpreview
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"html"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxPageSize is the maximum number of bytes read from a page. The
+	// metadata is in the head, so the rest of the page is never needed.
+	MaxPageSize = 512 * 1024
+	// MaxCached is the maximum number of previews cached in memory.
+	MaxCached = 512
+)
+
+// Preview is the metadata of a linked page.
+type Preview struct {
+	URL         string
+	SiteName    string
+	Title       string
+	Description string
+	Image       string // absolute URL; optional
+}
+
+type cacheEntry struct {
+	url     string
+	preview *Preview // nil if the page has no metadata
+}
+
+var cache = struct {
+	sync.Mutex
+	lru     *list.List // of *cacheEntry, most recent first
+	entries map[string]*list.Element
+}{
+	lru:     list.New(),
+	entries: map[string]*list.Element{},
+}
+
+// Fetch returns the preview of the page at the given URL. A nil preview is
+// returned if the page has no usable metadata. Results are cached in memory,
+// but errors are not, so failed fetches are retried next time.
+func Fetch(ctx context.Context, link string) (*Preview, error) {
+	if p, ok := cached(link); ok {
+		return p, nil
+	}
+
+	p, err := fetch(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+
+	store(link, p)
+	return p, nil
+}
+
+func cached(link string) (*Preview, bool) {
+	cache.Lock()
+	defer cache.Unlock()
+
+	elem, ok := cache.entries[link]
+	if !ok {
+		return nil, false
+	}
+
+	cache.lru.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).preview, true
+}
+
+func store(link string, p *Preview) {
+	cache.Lock()
+	defer cache.Unlock()
+
+	if elem, ok := cache.entries[link]; ok {
+		cache.lru.Remove(elem)
+	}
+
+	cache.entries[link] = cache.lru.PushFront(&cacheEntry{link, p})
+
+	for cache.lru.Len() > MaxCached {
+		last := cache.lru.Remove(cache.lru.Back()).(*cacheEntry)
+		delete(cache.entries, last.url)
+	}
+}
+
+func fetch(ctx context.Context, link string) (*Preview, error) {
+	r, err := get(ctx, link)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get page")
+	}
+	defer r.Body.Close()
+
+	// Only web pages have metadata.
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, nil
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, MaxPageSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read page")
+	}
+
+	base, err := url.Parse(link)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid URL")
+	}
+	// Resolve relative URLs against the final URL after redirects.
+	if r.Request != nil && r.Request.URL != nil {
+		base = r.Request.URL
+	}
+
+	page := parsePage(base, string(b))
+
+	// Fall back to oEmbed if the page has no OpenGraph or Twitter metadata.
+	if page.preview.Title == "" && page.oembed != "" {
+		if err := fetchOEmbed(ctx, page.oembed, &page.preview); err != nil {
+			return nil, errors.Wrap(err, "failed to get oEmbed")
+		}
+	}
+
+	if page.preview.Title == "" && page.preview.Description == "" {
+		return nil, nil
+	}
+
+	// The image is fetched by the image loader, so check it the same way.
+	if page.preview.Image != "" && !publicURL(ctx, page.preview.Image) {
+		page.preview.Image = ""
+	}
+
+	page.preview.URL = link
+	return &page.preview, nil
+}
+
+type parsedPage struct {
+	preview Preview
+	oembed  string // URL of the JSON oEmbed endpoint, if any
+}
+
+var (
+	headEnd   = regexp.MustCompile(`(?i)</head\s*>`)
+	tagRegex  = regexp.MustCompile(`(?is)<(meta|link)\s([^>]*)>`)
+	attrRegex = regexp.MustCompile(`([\w:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	titleTag  = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title\s*>`)
+)
+
+// metaKeys lists the keys of each field, from the most preferred.
+var metaKeys = struct {
+	siteName, title, description, image []string
+}{
+	siteName:    []string{"og:site_name", "application-name"},
+	title:       []string{"og:title", "twitter:title"},
+	description: []string{"og:description", "twitter:description", "description"},
+	image: []string{
+		"og:image:secure_url", "og:image:url", "og:image",
+		"twitter:image", "twitter:image:src",
+	},
+}
+
+// parsePage parses the metadata in the head of the page. It only uses regular
+// expressions, which is good enough for the few tags needed.
+func parsePage(base *url.URL, page string) parsedPage {
+	if loc := headEnd.FindStringIndex(page); loc != nil {
+		page = page[:loc[0]]
+	}
+
+	var meta = map[string]string{}
+	var parsed parsedPage
+
+	for _, tag := range tagRegex.FindAllStringSubmatch(page, -1) {
+		attrs := parseAttrs(tag[2])
+
+		switch strings.ToLower(tag[1]) {
+		case "meta":
+			key := attrs["property"]
+			if key == "" {
+				key = attrs["name"]
+			}
+			key = strings.ToLower(key)
+
+			// Keep the first value, such as the first of many og:image.
+			if _, ok := meta[key]; !ok && key != "" {
+				meta[key] = attrs["content"]
+			}
+
+		case "link":
+			if strings.EqualFold(attrs["type"], "application/json+oembed") {
+				parsed.oembed = resolve(base, attrs["href"])
+			}
+		}
+	}
+
+	p := &parsed.preview
+	p.SiteName = firstValue(meta, metaKeys.siteName)
+	p.Title = firstValue(meta, metaKeys.title)
+	p.Description = firstValue(meta, metaKeys.description)
+	p.Image = resolve(base, firstValue(meta, metaKeys.image))
+
+	// Only use the title tag if there's other metadata, since every page has
+	// one, and a preview of just the title isn't useful.
+	if p.Title == "" && (p.Description != "" || p.Image != "") {
+		if match := titleTag.FindStringSubmatch(page); match != nil {
+			p.Title = cleanText(match[1])
+		}
+	}
+
+	return parsed
+}
+
+func parseAttrs(attrs string) map[string]string {
+	var parsed = map[string]string{}
+
+	for _, match := range attrRegex.FindAllStringSubmatch(attrs, -1) {
+		// Only one of the value groups matches.
+		parsed[strings.ToLower(match[1])] = cleanText(match[2] + match[3] + match[4])
+	}
+
+	return parsed
+}
+
+func firstValue(meta map[string]string, keys []string) string {
+	for _, key := range keys {
+		if v := meta[key]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// cleanText unescapes the HTML text and collapses its whitespaces.
+func cleanText(s string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
+
+// resolve resolves the possibly relative reference into an absolute HTTP URL.
+// An empty string is returned if that's not possible.
+func resolve(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+
+	return u.String()
+}
+
+type oEmbed struct {
+	Title        string `json:"title"`
+	AuthorName   string `json:"author_name"`
+	ProviderName string `json:"provider_name"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+// publicURL returns true if the URL is a web URL whose host is public.
+func publicURL(ctx context.Context, link string) bool {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return checkPublic(ctx, u.Hostname()) == nil
+}
+
+func fetchOEmbed(ctx context.Context, endpoint string, p *Preview) error {
+	r, err := get(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	var embed oEmbed
+	if err := json.NewDecoder(io.LimitReader(r.Body, MaxPageSize)).Decode(&embed); err != nil {
+		return errors.Wrap(err, "failed to decode")
+	}
+
+	p.Title = cleanText(embed.Title)
+	p.Description = cleanText(embed.AuthorName)
+
+	if p.SiteName == "" {
+		p.SiteName = cleanText(embed.ProviderName)
+	}
+
+	if p.Image == "" {
+		if u, err := url.Parse(endpoint); err == nil {
+			p.Image = resolve(u, embed.ThumbnailURL)
+		}
+	}
+
+	return nil
+}
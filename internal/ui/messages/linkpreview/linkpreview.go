@@ -0,0 +1,146 @@
+// Package linkpreview unfurls links in messages into small preview cards using
+// the OpenGraph, Twitter card or oEmbed metadata of the linked pages.
+package linkpreview
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/diamondburned/cchat-gtk/internal/ui/config"
+	"github.com/diamondburned/cchat-gtk/internal/ui/rich/parser/markup"
+	"github.com/diamondburned/cchat/text"
+)
+
+// MaxPreviews is the maximum number of previews shown for a single message.
+const MaxPreviews = 3
+
+var (
+	// enabled is false by default, since unfurling contacts the servers of
+	// every link.
+	enabled   = false
+	allowlist []string
+	blocklist []string
+)
+
+func init() {
+	config.Register(config.Privacy, "Link Previews", config.Describe(
+		"Fetch the linked pages to show their title, description and image "+
+			"under messages. This contacts the servers of the links.",
+		config.Switch(&enabled, nil),
+	))
+	config.Register(config.Privacy, "Link Preview Allowlist", config.Describe(
+		"If not empty, only links to these domains (including subdomains) "+
+			"are previewed.",
+		config.StringList(&allowlist, validateDomain, nil),
+	))
+	config.Register(config.Privacy, "Link Preview Blocklist", config.Describe(
+		"Links to these domains (including subdomains) are never previewed.",
+		config.StringList(&blocklist, validateDomain, nil),
+	))
+}
+
+func validateDomain(domain string) error {
+	if strings.ContainsAny(domain, "/:@ ") {
+		return fmt.Errorf("%q is not a domain", domain)
+	}
+	return nil
+}
+
+// Links returns the links in the content that should be previewed, in order and
+// without duplicates. Links in hidden spoilers are skipped, since the preview
+// would show them. It returns nil if link previews are disabled. This function
+// is not thread-safe.
+func Links(content text.Rich) []string {
+	if !enabled {
+		return nil
+	}
+
+	var links []string
+	var seen = map[string]struct{}{}
+	var spoilers = hiddenSpoilers(content)
+
+	for _, segment := range content.Segments {
+		linker := segment.AsLinker()
+		if linker == nil {
+			continue
+		}
+
+		if start, end := segment.Bounds(); inSpoiler(spoilers, start, end) {
+			continue
+		}
+
+		link := linker.Link()
+		if _, ok := seen[link]; ok || !allowed(link) {
+			continue
+		}
+
+		seen[link] = struct{}{}
+		links = append(links, link)
+
+		if len(links) == MaxPreviews {
+			break
+		}
+	}
+
+	return links
+}
+
+// hiddenSpoilers returns the bounds of the spoilers in the content, or nil if
+// spoilers are always revealed.
+func hiddenSpoilers(content text.Rich) [][2]int {
+	if markup.RevealSpoilers {
+		return nil
+	}
+
+	var spoilers [][2]int
+
+	for _, segment := range content.Segments {
+		attributor := segment.AsAttributor()
+		if attributor != nil && attributor.Attribute().Has(text.AttributeSpoiler) {
+			start, end := segment.Bounds()
+			spoilers = append(spoilers, [2]int{start, end})
+		}
+	}
+
+	return spoilers
+}
+
+// inSpoiler returns true if the bounds overlap any of the spoilers.
+func inSpoiler(spoilers [][2]int, start, end int) bool {
+	for _, spoiler := range spoilers {
+		if start < spoiler[1] && spoiler[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed returns true if the link is a web page allowed by the allowlist and
+// the blocklist.
+func allowed(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+
+	if matchDomain(host, blocklist) {
+		return false
+	}
+
+	return len(allowlist) == 0 || matchDomain(host, allowlist)
+}
+
+// matchDomain returns true if the host is any of the domains or their
+// subdomains.
+func matchDomain(host string, domains []string) bool {
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.Trim(domain, "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
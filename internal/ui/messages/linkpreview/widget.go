@@ -0,0 +1,123 @@
+package linkpreview
+
+import (
+	"context"
+	"fmt"
+	"html"
+
+	"github.com/diamondburned/cchat-gtk/internal/gts"
+	"github.com/diamondburned/cchat-gtk/internal/gts/httputil"
+	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/gotk3/gotk3/pango"
+	"github.com/pkg/errors"
+)
+
+// ImageSize is the maximum size of the image in a preview.
+const ImageSize = 80
+
+var cardCSS = primitives.PrepareClassCSS("link-preview", `
+	.link-preview {
+		margin-top: 4px;
+		padding: 4px 8px;
+		border-left: 3px solid alpha(@theme_fg_color, 0.25);
+		border-radius: 0 4px 4px 0;
+		background-color: alpha(@theme_fg_color, 0.05);
+	}
+`)
+
+// New creates a box that shows the previews of the given links once they're
+// fetched, in the same order. Links without any metadata are skipped. Fetching
+// is canceled when the box is destroyed.
+func New(links []string) *gtk.Box {
+	box, _ := gtk.BoxNew(gtk.ORIENTATION_VERTICAL, 0)
+	box.Show()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	box.Connect("destroy", cancel)
+
+	for _, link := range links {
+		link := link
+
+		// Add a slot first to keep the order.
+		slot, _ := gtk.BoxNew(gtk.ORIENTATION_VERTICAL, 0)
+		box.PackStart(slot, false, false, 0)
+
+		gts.AsyncCtx(ctx, func() (func(), error) {
+			p, err := Fetch(ctx, link)
+			if err != nil {
+				if ctx.Err() != nil || errors.Cause(err) == httputil.ErrOffline {
+					return nil, nil
+				}
+				return nil, errors.Wrapf(err, "failed to preview %q", link)
+			}
+
+			if p == nil {
+				return nil, nil
+			}
+
+			return func() {
+				slot.PackStart(newCard(p), false, false, 0)
+				slot.Show()
+			}, nil
+		})
+	}
+
+	return box
+}
+
+func newCard(p *Preview) gtk.IWidget {
+	text, _ := gtk.BoxNew(gtk.ORIENTATION_VERTICAL, 2)
+	text.Show()
+
+	if p.SiteName != "" {
+		site := newCardLabel(fmt.Sprintf(
+			`<span size="small" alpha="65%%">%s</span>`, html.EscapeString(p.SiteName),
+		))
+		text.PackStart(site, false, false, 0)
+	}
+
+	if p.Title != "" {
+		title := newCardLabel(fmt.Sprintf(
+			`<a href="%s"><b>%s</b></a>`, html.EscapeString(p.URL), html.EscapeString(p.Title),
+		))
+		title.SetTooltipText(p.URL)
+		text.PackStart(title, false, false, 0)
+	}
+
+	if p.Description != "" {
+		desc := newCardLabel(fmt.Sprintf(
+			`<span size="small">%s</span>`, html.EscapeString(p.Description),
+		))
+		desc.SetLines(3)
+		desc.SetEllipsize(pango.ELLIPSIZE_END)
+		text.PackStart(desc, false, false, 0)
+	}
+
+	card, _ := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 8)
+	card.PackStart(text, true, true, 0)
+	card.Show()
+	cardCSS(card)
+
+	if p.Image != "" {
+		img, _ := gtk.ImageNew()
+		img.SetSizeRequest(ImageSize, ImageSize)
+		img.SetVAlign(gtk.ALIGN_START)
+		img.Show()
+
+		httputil.AsyncImage(context.Background(), img, p.Image)
+		card.PackEnd(img, false, false, 0)
+	}
+
+	return card
+}
+
+func newCardLabel(markup string) *gtk.Label {
+	l, _ := gtk.LabelNew("")
+	l.SetMarkup(markup)
+	l.SetXAlign(0)
+	l.SetLineWrap(true)
+	l.SetLineWrapMode(pango.WRAP_WORD_CHAR)
+	l.Show()
+	return l
+}
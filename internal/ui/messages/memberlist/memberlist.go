@@ -387,6 +387,7 @@ func NewMember(member cchat.ListMember) *Member {
 	m.Name.SetRenderer(func(rich text.Rich) markup.RenderOutput {
 		out := markup.RenderCmplxWithConfig(rich, markup.RenderConfig{
 			NoMentionLinks: true,
+			NoLinkify:      true,
 		})
 
 		if statusClass != "" {
@@ -445,6 +446,7 @@ func statusClassName(status cchat.Status) string {
 var noMentionLinks = markup.RenderConfig{
 	NoMentionLinks: true,
 	NoReferencing:  true,
+	NoLinkify:      true,
 }
 
 func (m *Member) Update(member cchat.ListMember) {
@@ -0,0 +1,118 @@
+package message
+
+import (
+	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
+	"github.com/diamondburned/cchat-gtk/internal/ui/rich"
+	"github.com/diamondburned/cchat-gtk/internal/ui/rich/codeblock"
+	"github.com/diamondburned/cchat-gtk/internal/ui/rich/labeluri"
+	"github.com/diamondburned/cchat-gtk/internal/ui/rich/parser/markup"
+	"github.com/diamondburned/cchat/text"
+	"github.com/gotk3/gotk3/gtk"
+)
+
+var quoteCSS = primitives.PrepareClassCSS("message-quote", `
+	.message-quote {
+		margin: 2px 0;
+		padding-left: 8px;
+		border-left: 3px solid alpha(@theme_fg_color, 0.3);
+	}
+`)
+
+// setBlocks shows the content. Blocks such as quotes and code are split out of
+// the content label into their own widgets, which are packed after it.
+func (m *State) setBlocks(content text.Rich, edited bool) {
+	for _, block := range m.blocks {
+		block.ToWidget().Destroy()
+	}
+	m.blocks = nil
+	m.blockLabels = nil
+
+	blocks := markup.SplitBlocks(content)
+
+	// Keep everything in the content label if there's nothing to split out.
+	if len(blocks) < 2 && (len(blocks) == 0 || blocks[0].Kind == markup.TextBlock) {
+		m.ContentBody.Show()
+		setContentLabel(m.ContentBody, content, edited)
+		return
+	}
+
+	// Code blocks can't have the edited mark, so add an empty label after them
+	// for it.
+	if edited && blocks[len(blocks)-1].Kind == markup.CodeBlock {
+		blocks = append(blocks, markup.Block{Kind: markup.TextBlock})
+	}
+
+	// Use the content label for the first block if it's regular text, since
+	// other widgets expect it to contain the content.
+	if blocks[0].Kind == markup.TextBlock {
+		m.ContentBody.Show()
+		setContentLabel(m.ContentBody, blocks[0].Content, false)
+		blocks = blocks[1:]
+	} else {
+		m.ContentBody.Hide()
+		setContentLabel(m.ContentBody, text.Plain(""), false)
+	}
+
+	for i, block := range blocks {
+		if block.Kind == markup.CodeBlock {
+			cb := codeblock.New(block.Content.Content, block.Language)
+			if block.Spoiler && !markup.RevealSpoilers {
+				cb.Conceal()
+			}
+
+			var w gtk.IWidget = cb
+			if block.Depth > 0 {
+				w = newQuote(w, block.Depth)
+			}
+
+			m.Content.PackStart(w, false, false, 0)
+			m.blocks = append(m.blocks, w)
+			continue
+		}
+
+		l := newContentLabel()
+		l.SetReferenceHighlighter(m.refer)
+		m.bindMenu(l)
+
+		// The edited mark goes after the last block.
+		setContentLabel(l, block.Content, edited && i == len(blocks)-1)
+
+		var w gtk.IWidget = l
+		if block.Kind == markup.QuoteBlock {
+			w = newQuote(l, block.Depth)
+		}
+
+		m.Content.PackStart(w, false, false, 0)
+		m.blocks = append(m.blocks, w)
+		m.blockLabels = append(m.blockLabels, l)
+	}
+}
+
+// setContentLabel sets the content into the label, using the renderer that
+// adds the edited mark if needed.
+func setContentLabel(l *labeluri.Label, content text.Rich, edited bool) {
+	var renderer rich.LabelRenderer
+	if edited {
+		renderer = renderEdited
+	}
+
+	// Empty the label first, so changing the renderer doesn't render the old
+	// content for nothing.
+	l.SetLabel(text.Rich{})
+	l.SetRenderer(renderer)
+	l.SetLabel(content)
+}
+
+// newQuote wraps the child in a bordered box for each level of quote.
+func newQuote(child gtk.IWidget, depth int) gtk.IWidget {
+	for i := 0; i < depth; i++ {
+		box, _ := gtk.BoxNew(gtk.ORIENTATION_VERTICAL, 0)
+		box.PackStart(child, false, false, 0)
+		box.Show()
+		quoteCSS(box)
+
+		child = box
+	}
+
+	return child
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-gtk/internal/gts"
+	"github.com/diamondburned/cchat-gtk/internal/ui/messages/linkpreview"
 	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
 	"github.com/diamondburned/cchat-gtk/internal/ui/primitives/menu"
 	"github.com/diamondburned/cchat-gtk/internal/ui/rich"
@@ -50,6 +52,18 @@ type State struct {
 	ContentBody      *labeluri.Label
 	ContentBodyStyle *gtk.StyleContext
 
+	// blocks contains the widgets of the blocks split out of the content, such
+	// as quotes. The content label shows the first block if it's regular text.
+	blocks      []gtk.IWidget
+	blockLabels []*labeluri.Label
+	refer       labeluri.ReferenceHighlighter
+
+	// previews contains the link previews under the content; nil if none.
+	previews *gtk.Box
+
+	// content is the last content given to UpdateContent.
+	content text.Rich
+
 	MenuItems []menu.Item
 }
 
@@ -71,19 +85,9 @@ func NewState(msg cchat.MessageCreate) *State {
 // NewEmptyState creates a new empty message state. The author should be set
 // immediately afterwards; it is invalid once the state is used.
 func NewEmptyState() *State {
-	ctbody := labeluri.NewLabel(text.Rich{})
-	ctbody.Tooltip = false
-	ctbody.SetHAlign(gtk.ALIGN_FILL)
-	ctbody.SetEllipsize(pango.ELLIPSIZE_NONE)
-	ctbody.SetLineWrap(true)
-	ctbody.SetLineWrapMode(pango.WRAP_WORD_CHAR)
-	ctbody.SetXAlign(0) // left align
-	ctbody.SetSelectable(true)
-	ctbody.SetTrackVisitedLinks(false)
-	ctbody.Show()
+	ctbody := newContentLabel()
 
 	ctbodyStyle, _ := ctbody.GetStyleContext()
-	ctbodyStyle.AddClass("message-content")
 
 	// Wrap the content label inside a content box.
 	ctbox, _ := gtk.BoxNew(gtk.ORIENTATION_VERTICAL, 0)
@@ -118,14 +122,45 @@ func NewEmptyState() *State {
 	row.Connect("destroy", func() { gc.Author.Name.Stop() })
 
 	// Bind the custom popup menu to the content label.
-	gc.ContentBody.Connect("populate-popup", func(l *gtk.Label, m *gtk.Menu) {
-		menu.MenuSeparator(m)
-		menu.MenuItems(m, gc.MenuItems)
-	})
+	gc.bindMenu(gc.ContentBody)
 
 	return gc
 }
 
+// newContentLabel creates a label for the message content.
+func newContentLabel() *labeluri.Label {
+	l := labeluri.NewLabel(text.Rich{})
+	l.Tooltip = false
+	l.SetHAlign(gtk.ALIGN_FILL)
+	l.SetEllipsize(pango.ELLIPSIZE_NONE)
+	l.SetLineWrap(true)
+	l.SetLineWrapMode(pango.WRAP_WORD_CHAR)
+	l.SetXAlign(0) // left align
+	l.SetSelectable(true)
+	l.SetTrackVisitedLinks(false)
+	l.Show()
+	primitives.AddClass(l, "message-content")
+
+	return l
+}
+
+// bindMenu binds the custom popup menu to the given content label.
+func (m *State) bindMenu(l *labeluri.Label) {
+	l.Connect("populate-popup", func(l *gtk.Label, menuw *gtk.Menu) {
+		menu.MenuSeparator(menuw)
+		menu.MenuItems(menuw, m.MenuItems)
+		menu.MenuSeparator(menuw)
+		menu.MenuItems(menuw, []menu.Item{
+			menu.SimpleItem("Copy as Markdown", func() {
+				gts.Clipboard.SetText(markup.RenderMarkdown(m.content))
+			}),
+			menu.SimpleItem("Copy as plain text", func() {
+				gts.Clipboard.SetText(markup.RenderPlain(m.content))
+			}),
+		})
+	})
+}
+
 // ClearBox clears the state's widget container.
 func (m *State) ClearBox() {
 	primitives.RemoveChildren(m)
@@ -163,20 +198,42 @@ func (m *State) SetClass(class string) {
 
 // SetReferenceHighlighter sets the reference highlighter into the message.
 func (m *State) SetReferenceHighlighter(r labeluri.ReferenceHighlighter) {
+	m.refer = r
 	m.ContentBody.SetReferenceHighlighter(r)
+
+	for _, l := range m.blockLabels {
+		l.SetReferenceHighlighter(r)
+	}
 }
 
 // UpdateContent replaces the internal content and the widget.
 func (m *State) UpdateContent(content text.Rich, edited bool) {
-	m.ContentBody.SetLabel(content)
+	m.content = content
+	m.setBlocks(content, edited)
+	m.updatePreviews(content)
+}
 
-	if edited {
-		m.ContentBody.SetRenderer(func(content text.Rich) markup.RenderOutput {
-			output := markup.RenderCmplx(content)
-			output.Markup += rich.Small(text.Plain("(edited)")).Markup
-			return output
-		})
+// renderEdited renders the content with an edited mark.
+func renderEdited(content text.Rich) markup.RenderOutput {
+	output := markup.RenderCmplx(content)
+	output.Markup += rich.Small(text.Plain("(edited)")).Markup
+	return output
+}
+
+// updatePreviews replaces the link previews with the ones for the new content.
+func (m *State) updatePreviews(content text.Rich) {
+	if m.previews != nil {
+		m.previews.Destroy()
+		m.previews = nil
+	}
+
+	links := linkpreview.Links(markup.Linkify(content))
+	if len(links) == 0 {
+		return
 	}
+
+	m.previews = linkpreview.New(links)
+	m.Content.PackStart(m.previews, false, false, 0)
 }
 
 func (m *State) Focusable() gtk.IWidget {
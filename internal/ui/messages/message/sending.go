@@ -21,6 +21,7 @@ type Presender interface {
 	SendingMessage() PresendMessage
 	SetDone(id cchat.ID)
 	SetLoading()
+	SetQueued()
 	SetSentError(err error)
 }
 
@@ -119,6 +120,13 @@ func (m *PresendState) SetLoading() {
 	}
 }
 
+// SetQueued greys the message like SetLoading, but indicates that the message
+// is waiting to be sent until we're back online.
+func (m *PresendState) SetQueued() {
+	m.SetLoading()
+	m.Content.SetTooltipText("Queued until back online")
+}
+
 // SetSentError sets the error into the message to notify the user.
 func (m *PresendState) SetSentError(err error) {
 	m.SetSensitive(true) // allow events incl right clicks
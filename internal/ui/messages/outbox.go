@@ -0,0 +1,71 @@
+package messages
+
+import (
+	"github.com/diamondburned/cchat-gtk/internal/gts"
+	"github.com/diamondburned/cchat-gtk/internal/offline"
+	"github.com/gotk3/gotk3/gtk"
+)
+
+// outbox contains the sends of messages queued while offline, in the order they
+// were queued. It is only accessed from the main thread. The sends are bound to
+// the connected sessions, so the outbox isn't kept across restarts.
+var outbox []func()
+
+func init() {
+	offline.OnChange(func(offline bool) {
+		if !offline {
+			flushOutbox()
+		}
+	})
+
+	gts.OnQuit(confirmOutbox)
+}
+
+func queueOutbox(send func()) {
+	outbox = append(outbox, send)
+}
+
+// flushOutbox sends all queued messages.
+func flushOutbox() {
+	queued := outbox
+	outbox = nil
+
+	for _, send := range queued {
+		send()
+	}
+}
+
+const quitResponse gtk.ResponseType = 1
+
+// confirmOutbox asks the user to confirm quitting if there are queued messages,
+// since they would be lost. It returns true if quitting should be stopped.
+func confirmOutbox() bool {
+	if len(outbox) == 0 {
+		return false
+	}
+
+	d := gtk.MessageDialogNew(
+		gts.App.Window, gtk.DIALOG_MODAL|gtk.DIALOG_DESTROY_WITH_PARENT,
+		gtk.MESSAGE_WARNING, gtk.BUTTONS_NONE,
+		"Quit without sending queued messages?",
+	)
+	d.FormatSecondaryText(
+		"%d message(s) are waiting to be sent once you're back online. "+
+			"They will be lost if you quit now.", len(outbox),
+	)
+	d.AddButton("_Cancel", gtk.RESPONSE_CANCEL)
+	d.AddButton("_Quit", quitResponse)
+	d.SetDefaultResponse(gtk.RESPONSE_CANCEL)
+
+	d.Connect("response", func(_ interface{}, resp gtk.ResponseType) {
+		d.Destroy()
+
+		if resp == quitResponse {
+			outbox = nil
+			gts.Quit()
+		}
+	})
+
+	d.Show()
+	return true
+}
@@ -153,6 +153,7 @@ func (c *Container) TrySubscribe(svmsg cchat.Messenger) bool {
 
 var noMentionLinks = markup.RenderConfig{
 	NoMentionLinks: true,
+	NoLinkify:      true,
 }
 
 func render(typers []typer) string {
@@ -3,12 +3,15 @@ package messages
 import (
 	"context"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/diamondburned/cchat"
 	"github.com/diamondburned/cchat-gtk/icons"
+	"github.com/diamondburned/cchat-gtk/internal/crash"
 	"github.com/diamondburned/cchat-gtk/internal/gts"
 	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/diamondburned/cchat-gtk/internal/offline"
 	"github.com/diamondburned/cchat-gtk/internal/ui/config"
 	"github.com/diamondburned/cchat-gtk/internal/ui/messages/container"
 	"github.com/diamondburned/cchat-gtk/internal/ui/messages/container/compact"
@@ -23,6 +26,7 @@ import (
 	"github.com/diamondburned/cchat-gtk/internal/ui/primitives/drag"
 	"github.com/diamondburned/cchat-gtk/internal/ui/primitives/menu"
 	"github.com/diamondburned/cchat-gtk/internal/ui/rich"
+	"github.com/diamondburned/cchat-gtk/internal/ui/service/chanconf"
 	"github.com/diamondburned/cchat-gtk/internal/ui/service/session"
 	"github.com/diamondburned/cchat-gtk/internal/ui/service/session/server"
 	"github.com/diamondburned/cchat-gtk/internal/ui/service/session/server/traverse"
@@ -39,10 +43,13 @@ const (
 var msgIndex = cozyMessage
 
 func init() {
-	config.AppearanceAdd("Message Display", config.Combo(
-		&msgIndex, // 0 or 1
-		[]string{"Cozy", "Compact"},
-		nil,
+	config.AppearanceAdd("Message Display", config.Describe(
+		"Cozy groups messages under their author; Compact shows one line per message.",
+		config.Combo(
+			&msgIndex, // 0 or 1
+			[]string{"Cozy", "Compact"},
+			nil,
+		),
 	))
 }
 
@@ -231,8 +238,12 @@ func (v *View) Bottomed() bool { return v.Scroller.Bottomed }
 
 // Reset resets the message view.
 func (v *View) Reset() {
-	v.FaceView.Reset() // Switch back to the main screen.
+	chanconf.Apply(nil) // Revert to the global settings.
+	v.FaceView.Reset()  // Switch back to the main screen.
 	v.reset()
+
+	crash.SetState("Session", "")
+	crash.SetState("Server", "")
 }
 
 // reset resets the message view, but does not change visible containers.
@@ -290,6 +301,14 @@ func (v *View) JoinServer(ses *session.Row, srv *server.ServerRow, bc traverse.B
 	v.FaceView.SetLoading()
 	v.ctrl.OnMessageBusy()
 
+	// Apply the channel's own settings, which the message container created
+	// by reset may depend on.
+	chanconf.Apply(bc)
+
+	// Note down what's opened in case of a crash.
+	crash.SetState("Session", ses.Session.ID())
+	crash.SetState("Server", strings.Join(traverse.TryID(bc), "/"))
+
 	// Reset before setting.
 	v.reset()
 
@@ -312,6 +331,8 @@ func (v *View) JoinServer(ses *session.Row, srv *server.ServerRow, bc traverse.B
 	v.Container.SetSelf(v.InputView.Username.State)
 
 	go func() {
+		defer crash.Recover()
+
 		// We can use a background context here, as the user can't go anywhere
 		// that would require cancellation anyway. This is done in ui.go.
 		s, err := messenger.JoinServer(context.Background(), v.Container)
@@ -432,10 +453,26 @@ func (v *View) retryMessage(state *message.PresendState, presend container.Prese
 		return
 	}
 
+	v.sendMessage(sender, state, presend)
+}
+
+// sendMessage sends the message using the given sender. If we're offline, then
+// the message is queued into the outbox and sent once we're back online.
+func (v *View) sendMessage(
+	sender cchat.Sender, state *message.PresendState, presend container.PresendMessageRow) {
+
+	if offline.IsOffline() {
+		presend.SetQueued()
+		queueOutbox(func() { v.sendMessage(sender, state, presend) })
+		return
+	}
+
 	// Ensure the message is set to loading.
 	presend.SetLoading()
 
 	go func() {
+		defer crash.Recover()
+
 		err := sender.Send(presend.SendingMessage())
 		if err == nil {
 			return
@@ -501,6 +538,8 @@ func (v *View) BindMenu(msg container.MessageRow) {
 func (v *View) makeActionItem(action, msgID string) menu.Item {
 	return menu.SimpleItem(action, func() {
 		go func() {
+			defer crash.Recover()
+
 			// Run, get the error, and try to log it. The logger will ignore nil
 			// errors.
 			err := v.state.actioner.Do(action, msgID)
@@ -1,9 +1,10 @@
 package actions
 
 import (
-	"log"
+	"fmt"
 	"strings"
 
+	"github.com/diamondburned/cchat-gtk/internal/crash"
 	"github.com/gotk3/gotk3/glib"
 )
 
@@ -67,7 +68,7 @@ func ActionName(label string) (actionName string) {
 	actionName = strings.Replace(label, " ", "-", -1)
 
 	if !glib.ActionNameIsValid(actionName) {
-		log.Panicf("Label makes for invalid action name %q\n", actionName)
+		panic(crash.Reported(fmt.Sprintf("Label makes for invalid action name %q", actionName)))
 	}
 
 	return
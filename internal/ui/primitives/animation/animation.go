@@ -0,0 +1,69 @@
+// Package animation provides what gotk3 lacks for playing PixbufAnimations
+// manually, which is needed for widgets that can only draw Pixbufs.
+package animation
+
+// #cgo pkg-config: gdk-pixbuf-2.0
+// #include <gdk-pixbuf/gdk-pixbuf.h>
+import "C"
+
+import (
+	"runtime"
+	"time"
+	"unsafe"
+
+	"github.com/gotk3/gotk3/gdk"
+	"github.com/gotk3/gotk3/glib"
+)
+
+func native(anim *gdk.PixbufAnimation) *C.GdkPixbufAnimation {
+	return (*C.GdkPixbufAnimation)(unsafe.Pointer(anim.Native()))
+}
+
+// IsStatic returns true if the animation only has a single frame, which is the
+// case for all images loaded from formats that can't animate.
+func IsStatic(anim *gdk.PixbufAnimation) bool {
+	return C.gdk_pixbuf_animation_is_static_image(native(anim)) != 0
+}
+
+// Iter iterates over the frames of an animation.
+type Iter struct {
+	native *C.GdkPixbufAnimationIter
+	anim   *gdk.PixbufAnimation // keep alive
+}
+
+// NewIter creates an iterator starting at the first frame.
+func NewIter(anim *gdk.PixbufAnimation) *Iter {
+	iter := &Iter{
+		native: C.gdk_pixbuf_animation_get_iter(native(anim), nil),
+		anim:   anim,
+	}
+
+	runtime.SetFinalizer(iter, func(iter *Iter) {
+		C.g_object_unref(C.gpointer(iter.native))
+	})
+
+	return iter
+}
+
+// Pixbuf returns the current frame.
+func (iter *Iter) Pixbuf() *gdk.Pixbuf {
+	c := C.gdk_pixbuf_animation_iter_get_pixbuf(iter.native)
+	obj := glib.Take(unsafe.Pointer(c))
+	return &gdk.Pixbuf{Object: obj}
+}
+
+// Delay returns how long the current frame should be shown. It returns a
+// negative duration if the frame should be shown forever.
+func (iter *Iter) Delay() time.Duration {
+	ms := C.gdk_pixbuf_animation_iter_get_delay_time(iter.native)
+	if ms < 0 {
+		return -1
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// Advance moves the iterator to the frame for the current time. It returns true
+// if the frame has changed.
+func (iter *Iter) Advance() bool {
+	return C.gdk_pixbuf_animation_iter_advance(iter.native, nil) != 0
+}
@@ -2,15 +2,16 @@ package roundimage
 
 import (
 	"context"
+	"time"
 
 	"github.com/diamondburned/cchat-gtk/internal/gts/httputil"
+	"github.com/diamondburned/cchat-gtk/internal/ui/primitives/animation"
 	"github.com/diamondburned/handy"
 	"github.com/gotk3/gotk3/gdk"
+	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
 )
 
-// TODO: GIF support
-
 // TextSetter is an interface for setting texts.
 type TextSetter interface {
 	SetText(text string)
@@ -22,13 +23,17 @@ func TrySetText(imager Imager, text string) {
 	}
 }
 
-// Avatar is a static HdyAvatar container.
+// Avatar is a HdyAvatar container. Since HdyAvatar can only draw pixbufs,
+// animations are played by drawing each frame manually.
 type Avatar struct {
 	handy.Avatar
 	pixbuf *gdk.Pixbuf
 	url    string
 	size   int
 	cancel context.CancelFunc
+
+	animation *gdk.PixbufAnimation
+	iter      *animation.Iter // current animation frame
 }
 
 // Make a better API that allows scaling.
@@ -46,6 +51,7 @@ func NewAvatar(size int) *Avatar {
 	}
 	// Set the load function. This should hopefully trigger a reload.
 	avatar.SetImageLoadFunc(avatar.loadFunc)
+	avatar.Connect("destroy", avatar.stopAnimation)
 
 	return &avatar
 }
@@ -110,27 +116,62 @@ func (a *Avatar) SetImageURL(url string) {
 // SetFromPixbuf sets the pixbuf.
 func (a *Avatar) SetFromPixbuf(pb *gdk.Pixbuf) {
 	a.cancelCtx()
-	a.pixbuf = pb
-	// a.Avatar.SetImageLoadFunc(a.loadFunc)
-	a.Avatar.QueueDraw()
+	a.stopAnimation()
+	a.setPixbuf(pb)
 }
 
-// SetFromAnimation sets the first frame of the animation.
+// SetFromAnimation plays the animation.
 func (a *Avatar) SetFromAnimation(pa *gdk.PixbufAnimation) {
 	a.cancelCtx()
-	a.pixbuf = pa.GetStaticImage()
+	a.stopAnimation()
+
+	a.animation = pa
+	a.iter = animation.NewIter(pa)
+	a.setPixbuf(a.iter.Pixbuf())
+	a.queueFrame(a.iter)
+}
+
+func (a *Avatar) setPixbuf(pb *gdk.Pixbuf) {
+	a.pixbuf = pb
 	// a.Avatar.SetImageLoadFunc(a.loadFunc)
 	a.Avatar.QueueDraw()
 }
 
+// queueFrame draws the next frame after the current one's delay, unless the
+// animation is stopped by then.
+func (a *Avatar) queueFrame(iter *animation.Iter) {
+	delay := iter.Delay()
+	if delay < 0 {
+		return
+	}
+
+	glib.TimeoutAdd(uint(delay/time.Millisecond), func() bool {
+		if a.iter != iter {
+			return false
+		}
+
+		if iter.Advance() {
+			a.setPixbuf(iter.Pixbuf())
+		}
+
+		a.queueFrame(iter)
+		return false
+	})
+}
+
+func (a *Avatar) stopAnimation() {
+	a.animation = nil
+	a.iter = nil
+}
+
 // GetPixbuf returns the underlying pixbuf.
 func (a *Avatar) GetPixbuf() *gdk.Pixbuf {
 	return a.pixbuf
 }
 
-// GetAnimation returns nil.
+// GetAnimation returns the playing animation, or nil if there's none.
 func (a *Avatar) GetAnimation() *gdk.PixbufAnimation {
-	return nil
+	return a.animation
 }
 
 // GetImage returns nil.
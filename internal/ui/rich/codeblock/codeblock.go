@@ -0,0 +1,200 @@
+// Package codeblock provides a widget that shows a syntax highlighted block of
+// code.
+package codeblock
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/diamondburned/cchat-gtk/internal/gts"
+	"github.com/diamondburned/cchat-gtk/internal/ui/config"
+	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
+	"github.com/diamondburned/cchat-gtk/internal/ui/rich/parser/hl"
+	"github.com/diamondburned/cchat-gtk/internal/ui/rich/parser/markup"
+	"github.com/gotk3/gotk3/gtk"
+)
+
+// LineNumbers, if true, shows line numbers next to the code in new code blocks.
+var LineNumbers = false
+
+func init() {
+	config.AppearanceAdd("Code Line Numbers", config.Describe(
+		"Show line numbers next to the code in code blocks.",
+		config.Switch(&LineNumbers, nil),
+	))
+}
+
+var codeblockCSS = primitives.PrepareClassCSS("message-codeblock", `
+	.message-codeblock {
+		margin: 2px 0;
+		border-radius: 4px;
+		border: 1px solid alpha(@theme_fg_color, 0.15);
+		background-color: alpha(@theme_fg_color, 0.05);
+	}
+`)
+
+var headerCSS = primitives.PrepareClassCSS("codeblock-header", `
+	.codeblock-header {
+		padding-left: 6px;
+		border-bottom: 1px solid alpha(@theme_fg_color, 0.1);
+	}
+	.codeblock-header button {
+		padding: 0 4px;
+		min-height: 0;
+	}
+`)
+
+var codeCSS = primitives.PrepareClassCSS("codeblock-code", `
+	.codeblock-code {
+		padding: 4px 6px;
+	}
+`)
+
+var linesCSS = primitives.PrepareClassCSS("codeblock-lines", `
+	.codeblock-lines {
+		padding: 4px 6px;
+		border-right: 1px solid alpha(@theme_fg_color, 0.1);
+		color: alpha(@theme_fg_color, 0.5);
+	}
+`)
+
+var spoilerCSS = primitives.PrepareClassCSS("codeblock-spoiler", `
+	.codeblock-spoiler {
+		margin: 4px 6px;
+	}
+`)
+
+// Codeblock is a block of code with a header that has the language and a button
+// to copy the code. Long lines are scrolled horizontally instead of wrapped.
+type Codeblock struct {
+	*gtk.Box
+	Header *gtk.Box
+	Code   *gtk.Label
+	Lines  *gtk.Label // nil if line numbers are off
+
+	body    *gtk.ScrolledWindow
+	copyBtn *gtk.Button
+	code    string
+}
+
+// New creates a new code block. The language is guessed from the code if it's
+// empty.
+func New(code, language string) *Codeblock {
+	language = hl.LanguageName(language, code)
+
+	name, _ := gtk.LabelNew(language)
+	name.SetXAlign(0)
+	name.SetSingleLineMode(true)
+	name.Show()
+	primitives.AddClass(name, "dim-label")
+
+	copyBtn, _ := gtk.ButtonNewFromIconName("edit-copy-symbolic", gtk.ICON_SIZE_MENU)
+	copyBtn.SetRelief(gtk.RELIEF_NONE)
+	copyBtn.SetTooltipText("Copy code")
+	copyBtn.Connect("clicked", func(*gtk.Button) { gts.Clipboard.SetText(code) })
+	copyBtn.Show()
+
+	header, _ := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 0)
+	header.PackStart(name, true, true, 0)
+	header.PackEnd(copyBtn, false, false, 0)
+	header.Show()
+	headerCSS(header)
+
+	label, _ := gtk.LabelNew("")
+	label.SetMarkup(markup.RenderCodeblock(code, language))
+	label.SetXAlign(0)
+	label.SetYAlign(0)
+	label.SetSelectable(true)
+	label.SetCanFocus(false)
+	label.Show()
+	codeCSS(label)
+
+	body, _ := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 0)
+	body.Show()
+
+	var lines *gtk.Label
+	if LineNumbers {
+		lines, _ = gtk.LabelNew("")
+		lines.SetMarkup(lineNumbers(code))
+		lines.SetXAlign(1)
+		lines.SetYAlign(0)
+		lines.Show()
+		linesCSS(lines)
+
+		body.PackStart(lines, false, false, 0)
+	}
+
+	body.PackStart(label, true, true, 0)
+
+	// Only scroll horizontally. The height is always the code's height.
+	sw, _ := gtk.ScrolledWindowNew(nil, nil)
+	sw.SetPolicy(gtk.POLICY_AUTOMATIC, gtk.POLICY_NEVER)
+	sw.SetPropagateNaturalHeight(true)
+	sw.Add(body)
+	sw.Show()
+
+	box, _ := gtk.BoxNew(gtk.ORIENTATION_VERTICAL, 0)
+	box.PackStart(header, false, false, 0)
+	box.PackStart(sw, false, false, 0)
+	box.Show()
+	codeblockCSS(box)
+
+	// Highlight the code again with the new style when it changes, such as
+	// when switching to a dark theme.
+	remove := hl.OnStyleChange(func() {
+		label.SetMarkup(markup.RenderCodeblock(code, language))
+	})
+	box.Connect("destroy", remove)
+
+	return &Codeblock{
+		Box:     box,
+		Header:  header,
+		Code:    label,
+		Lines:   lines,
+		body:    sw,
+		copyBtn: copyBtn,
+		code:    code,
+	}
+}
+
+// Conceal hides the code behind a button that shows it, which is used for code
+// in spoilers.
+func (c *Codeblock) Conceal() {
+	c.body.Hide()
+	c.copyBtn.Hide()
+
+	reveal, _ := gtk.ButtonNewWithLabel("Show spoiler")
+	reveal.SetHAlign(gtk.ALIGN_START)
+	reveal.Show()
+	spoilerCSS(reveal)
+
+	reveal.Connect("clicked", func(*gtk.Button) {
+		reveal.Destroy()
+		c.body.Show()
+		c.copyBtn.Show()
+	})
+
+	c.Box.PackStart(reveal, false, false, 0)
+}
+
+// Text returns the code in plain text.
+func (c *Codeblock) Text() string {
+	return c.code
+}
+
+// lineNumbers returns the markup of the line numbers for the code. It uses the
+// same font as the code, so the numbers line up.
+func lineNumbers(code string) string {
+	var count = strings.Count(code, "\n") + 1
+	var lines = make([]string, count)
+	for i := range lines {
+		lines[i] = strconv.Itoa(i + 1)
+	}
+
+	return fmt.Sprintf(
+		`<span font_family="monospace">%s</span>`,
+		html.EscapeString(strings.Join(lines, "\n")),
+	)
+}
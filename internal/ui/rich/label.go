@@ -16,6 +16,8 @@ func RenderSkipImages(rich text.Rich) markup.RenderOutput {
 	return markup.RenderCmplxWithConfig(rich, markup.RenderConfig{
 		SkipImages:     true,
 		NoMentionLinks: true,
+		NoSpoilerLinks: true,
+		NoLinkify:      true,
 	})
 }
 
@@ -91,6 +93,12 @@ func (l *Label) SetLabel(content text.Rich) {
 	}
 }
 
+// RevealSpoiler shows the given hidden spoiler as regular text. The spoiler is
+// hidden again when the label is set to a new content.
+func (l *Label) RevealSpoiler(spoiler markup.SpoilerSegment) {
+	l.SetLabel(markup.RevealSpoiler(l.label, spoiler.Index))
+}
+
 // SetRenderer sets a custom renderer. If the given renderer is nil, then the
 // default markup renderer is used instead. The label is automatically updated.
 func (l *Label) SetRenderer(renderer LabelRenderer) {
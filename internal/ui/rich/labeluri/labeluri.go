@@ -99,6 +99,10 @@ func (bound *BoundBox) activate(uri string, ptr gdk.Rectangle) bool {
 
 		return true
 
+	case markup.SpoilerSegment:
+		bound.label.RevealSpoiler(segment)
+		return true
+
 	default:
 		return false
 	}
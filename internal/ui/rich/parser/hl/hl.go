@@ -25,19 +25,51 @@ var (
 )
 
 func init() {
-	var name = "algol_nu" // default
-	ChangeStyle(name)
-	config.AppearanceAdd("Code Highlight Style", config.InputEntry(&name, ChangeStyle))
+	ChangeStyle(lightStyle)
+
+	config.AppearanceAdd("Code Highlight Style (Light)", config.Describe(
+		"The style used to highlight code blocks with light themes.",
+		config.Choice(&lightStyle, styles.Names(), func(string) error { return updateStyle() }),
+	))
+	config.AppearanceAdd("Code Highlight Style (Dark)", config.Describe(
+		"The style used to highlight code blocks with dark themes.",
+		config.Choice(&darkStyle, styles.Names(), func(string) error { return updateStyle() }),
+	))
+
+	// The old single style was picked for the default light theme.
+	config.RegisterMigration(2, config.RenameEntry(
+		config.Appearance, "Code Highlight Style", "Code Highlight Style (Light)",
+	))
 }
 
 func Tokenize(language, source string) chroma.Iterator {
-	var lexer = getLexer(language)
-	if lexer == nil {
-		lexer = lexers.Fallback
+	i, _ := Lexer(language, source).Tokenise(nil, source)
+	return i
+}
+
+// Lexer returns the lexer for the given language. If the language is empty or
+// unknown, then the language is guessed from the source, and the plain text
+// lexer is returned if that fails.
+func Lexer(language, source string) chroma.Lexer {
+	if lexer := getLexer(language); lexer != nil {
+		return lexer
 	}
 
-	i, _ := lexer.Tokenise(nil, source)
-	return i
+	if lexer := lexers.Analyse(source); lexer != nil {
+		return lexer
+	}
+
+	return lexers.Fallback
+}
+
+// LanguageName returns the name of the language used to highlight the source,
+// or an empty string if it's plain text.
+func LanguageName(language, source string) string {
+	lexer := Lexer(language, source)
+	if lexer == lexers.Fallback {
+		return ""
+	}
+	return lexer.Config().Name
 }
 
 func Segments(appendmap *attrmap.AppendMap, src string, start, end int, lang string) {
@@ -48,10 +80,13 @@ func Segments(appendmap *attrmap.AppendMap, src string, start, end int, lang str
 	)
 
 	if i := Tokenize(lang, src[start:end]); i != nil {
-		fmtter.segments(appendmap, start, i)
+		fmtter.segments(appendmap, start, end, i)
 	}
 }
 
+// ChangeStyle changes the style used to highlight code until the next time the
+// theme or the configured styles change. Callbacks added with OnStyleChange are
+// called.
 func ChangeStyle(styleName string) error {
 	s := styles.Get(styleName)
 
@@ -61,6 +96,11 @@ func ChangeStyle(styleName string) error {
 	}
 
 	css = styleToCSS(s)
+
+	for _, f := range handlers {
+		f()
+	}
+
 	return nil
 }
 
@@ -88,10 +128,16 @@ func (f *formatter) reset() {
 	f.highlightRanges = f.highlightRanges[:0]
 }
 
-func (f *formatter) segments(appendmap *attrmap.AppendMap, offset int, iter chroma.Iterator) {
+func (f *formatter) segments(appendmap *attrmap.AppendMap, offset, end int, iter chroma.Iterator) {
 	f.reset()
 
 	for _, token := range iter.Tokens() {
+		// Some lexers add a trailing new line, which would put the tags past
+		// the end of the code.
+		if offset >= end {
+			break
+		}
+
 		attr := f.styleAttr(token.Type)
 
 		if attr != "" {
@@ -99,6 +145,9 @@ func (f *formatter) segments(appendmap *attrmap.AppendMap, offset int, iter chro
 		}
 
 		offset += len(token.Value)
+		if offset > end {
+			offset = end
+		}
 
 		if attr != "" {
 			appendmap.Close(offset, "</span>")
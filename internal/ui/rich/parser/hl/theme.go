@@ -0,0 +1,81 @@
+package hl
+
+import (
+	"strings"
+
+	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/pkg/errors"
+)
+
+var (
+	lightStyle = "algol_nu"
+	darkStyle  = "monokai"
+
+	// dark is true if the current theme is dark.
+	dark bool
+
+	handlers = map[int]func(){}
+	serial   int
+)
+
+// WatchTheme picks the light or dark style for the current theme, and switches
+// between them whenever the theme changes. It must be called after Gtk is
+// initialized.
+func WatchTheme() {
+	settings, err := gtk.SettingsGetDefault()
+	if err != nil {
+		log.Error(errors.Wrap(err, "failed to get settings to watch the theme"))
+		return
+	}
+
+	update := func() {
+		dark = isDark(settings)
+		if err := updateStyle(); err != nil {
+			log.Error(errors.Wrap(err, "failed to change the highlight style"))
+		}
+	}
+
+	settings.Connect("notify::gtk-application-prefer-dark-theme", update)
+	settings.Connect("notify::gtk-theme-name", update)
+
+	update()
+}
+
+// IsDark returns true if the current theme is dark.
+func IsDark() bool {
+	return dark
+}
+
+// OnStyleChange adds a callback that is called when the highlight style
+// changes, so that highlighted code can be rendered again. The returned
+// callback removes it.
+func OnStyleChange(f func()) (remove func()) {
+	id := serial
+	serial++
+	handlers[id] = f
+
+	return func() { delete(handlers, id) }
+}
+
+// updateStyle changes to the configured style for the current theme.
+func updateStyle() error {
+	if dark {
+		return ChangeStyle(darkStyle)
+	}
+	return ChangeStyle(lightStyle)
+}
+
+// isDark returns true if the dark variant of the theme is preferred or if the
+// theme is a dark theme, such as Adwaita-dark or Adwaita:dark.
+func isDark(settings *gtk.Settings) bool {
+	if v, _ := settings.GetProperty("gtk-application-prefer-dark-theme"); v == true {
+		return true
+	}
+
+	v, _ := settings.GetProperty("gtk-theme-name")
+	name, _ := v.(string)
+	name = strings.ToLower(name)
+
+	return strings.HasSuffix(name, "-dark") || strings.HasSuffix(name, ":dark")
+}
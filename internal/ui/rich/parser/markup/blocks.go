@@ -0,0 +1,236 @@
+package markup
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/diamondburned/cchat/text"
+)
+
+// BlockKind is the kind of a block.
+type BlockKind uint8
+
+const (
+	// TextBlock is regular text.
+	TextBlock BlockKind = iota
+	// QuoteBlock is quoted text.
+	QuoteBlock
+	// CodeBlock is a code block. Its content is plain text.
+	CodeBlock
+)
+
+// Block is a part of a content that is shown as its own widget.
+type Block struct {
+	Kind BlockKind
+	// Depth is the number of quotes that the block is in. Code blocks can be
+	// quoted as well.
+	Depth   int
+	Content text.Rich
+	// Language is the language of a code block as given by the backend. It
+	// may be empty.
+	Language string
+	// Spoiler is true if the code block is in a spoiler, which should be
+	// hidden unless RevealSpoilers is true.
+	Spoiler bool
+}
+
+// SplitBlocks splits the content into blocks of regular text, quoted text and
+// code, so that they can be shown in their own widgets. Nested quotes are split
+// into their own blocks with a higher depth. The quote and code segments are
+// removed from the blocks, and other segments are clipped to each block. A
+// single text block with the original content is returned if there's nothing
+// to split.
+func SplitBlocks(content text.Rich) []Block {
+	var quotes [][2]int
+	var codes []codeRegion
+	var spoilers [][2]int
+	var points = []int{0, len(content.Content)}
+
+	for _, segment := range content.Segments {
+		if attributor := segment.AsAttributor(); attributor != nil {
+			if attributor.Attribute().Has(text.AttributeSpoiler) {
+				start, end := clampBounds(segment, content.Content)
+				spoilers = append(spoilers, [2]int{start, end})
+			}
+		}
+
+		quoteblocker := segment.AsQuoteblocker()
+		codeblocker := segment.AsCodeblocker()
+		if quoteblocker == nil && codeblocker == nil {
+			continue
+		}
+
+		start, end := clampBounds(segment, content.Content)
+		if start >= end {
+			continue
+		}
+
+		if quoteblocker != nil {
+			quotes = append(quotes, [2]int{start, end})
+		}
+		if codeblocker != nil {
+			codes = append(codes, codeRegion{start, end, codeblocker.CodeblockLanguage()})
+		}
+
+		points = append(points, start, end)
+	}
+
+	if len(quotes) == 0 && len(codes) == 0 {
+		return []Block{{Kind: TextBlock, Content: content}}
+	}
+
+	sort.Ints(points)
+
+	type span struct{ start, end, depth, code int }
+	var spans []span
+
+	for i := 0; i+1 < len(points); i++ {
+		start, end := points[i], points[i+1]
+		if start == end {
+			continue
+		}
+
+		var depth int
+		for _, quote := range quotes {
+			if quote[0] <= start && end <= quote[1] {
+				depth++
+			}
+		}
+
+		// Use the outermost code block if they're nested, since code can't
+		// contain code.
+		var code = -1
+		for i, region := range codes {
+			if region.start <= start && end <= region.end {
+				code = i
+				break
+			}
+		}
+
+		// Merge with the previous span if it's the same kind of block.
+		if last := len(spans) - 1; last >= 0 {
+			if spans[last].depth == depth && spans[last].code == code {
+				spans[last].end = end
+				continue
+			}
+		}
+
+		spans = append(spans, span{start, end, depth, code})
+	}
+
+	var blocks = make([]Block, 0, len(spans))
+
+	for _, span := range spans {
+		start, end := trimNewlines(content.Content, span.start, span.end)
+		if start >= end {
+			continue
+		}
+
+		block := Block{
+			Kind:  TextBlock,
+			Depth: span.depth,
+		}
+
+		if span.code >= 0 {
+			block.Kind = CodeBlock
+			block.Content = text.Plain(content.Content[start:end])
+			block.Language = codes[span.code].language
+			block.Spoiler = overlapsAny(spoilers, start, end)
+		} else {
+			if span.depth > 0 {
+				block.Kind = QuoteBlock
+			}
+			block.Content = clipRich(content, start, end)
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	return blocks
+}
+
+// overlapsAny returns true if the bounds overlap any of the regions.
+func overlapsAny(regions [][2]int, start, end int) bool {
+	for _, region := range regions {
+		if start < region[1] && region[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+type codeRegion struct {
+	start, end int
+	language   string
+}
+
+// trimNewlines moves the bounds inwards to exclude the surrounding new lines,
+// which are implied by the blocks.
+func trimNewlines(content string, start, end int) (int, int) {
+	sub := content[start:end]
+	trimmed := strings.TrimLeft(sub, "\n")
+	start += len(sub) - len(trimmed)
+	end = start + len(strings.TrimRight(trimmed, "\n"))
+	return start, end
+}
+
+// clampBounds returns the segment's bounds clamped to the content and widened
+// to not cut characters in half.
+func clampBounds(segment text.Segment, content string) (start, end int) {
+	start, end = segment.Bounds()
+	if start < 0 {
+		start = 0
+	}
+	if end > len(content) {
+		end = len(content)
+	}
+	return runeStart(content, start), runeEnd(content, end)
+}
+
+// clipRich returns the content between the bounds with its segments clipped to
+// the bounds. Quote and code segments are skipped.
+func clipRich(content text.Rich, start, end int) text.Rich {
+	clipped := text.Rich{
+		Content:  content.Content[start:end],
+		Segments: make([]text.Segment, 0, len(content.Segments)),
+	}
+
+	for _, segment := range content.Segments {
+		if segment.AsQuoteblocker() != nil || segment.AsCodeblocker() != nil {
+			continue
+		}
+
+		i, j := clampBounds(segment, content.Content)
+
+		switch {
+		case i == j:
+			// Inline images are empty. Keep the ones within the bounds, and the
+			// ones at the end unless they're at the start of the next block.
+			atEnd := i == end && (end == len(content.Content) || content.Content[end] == '\n')
+			if i < start || (i >= end && !atEnd) {
+				continue
+			}
+		case j <= start || i >= end:
+			continue
+		}
+
+		if i < start {
+			i = start
+		}
+		if j > end {
+			j = end
+		}
+
+		clipped.Segments = append(clipped.Segments, clippedSegment{segment, i - start, j - start})
+	}
+
+	return clipped
+}
+
+// clippedSegment is a segment with its bounds moved into a block.
+type clippedSegment struct {
+	text.Segment
+	start, end int
+}
+
+func (s clippedSegment) Bounds() (start, end int) { return s.start, s.end }
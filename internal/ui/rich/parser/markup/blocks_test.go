@@ -0,0 +1,27 @@
+package markup
+
+import (
+	"testing"
+
+	"github.com/diamondburned/cchat/text"
+)
+
+func TestSplitBlocksSpoiler(t *testing.T) {
+	content := text.Rich{Content: "a\nfmt.Println()\nb\ncode"}
+	content.Segments = []text.Segment{
+		testSegment{start: 0, end: 15, attr: text.AttributeSpoiler},
+		testSegment{start: 2, end: 15, code: lang("go")},
+		testSegment{start: 18, end: 22, code: lang("")},
+	}
+
+	var spoilers []bool
+	for _, block := range SplitBlocks(content) {
+		if block.Kind == CodeBlock {
+			spoilers = append(spoilers, block.Spoiler)
+		}
+	}
+
+	if len(spoilers) != 2 || !spoilers[0] || spoilers[1] {
+		t.Fatal("Unexpected spoiler code blocks:", spoilers)
+	}
+}
@@ -0,0 +1,147 @@
+//go:build go1.18
+// +build go1.18
+
+package markup
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/diamondburned/cchat/text"
+)
+
+func FuzzRenderCmplx(f *testing.F) {
+	f.Add("hello, world", []byte{0, 5, 1, 1, 3, 9, 2, 0})
+	f.Add("one two three", []byte{4, 13, 0, 2, 0, 7, 9, 1, 2, 2, 12, 0})
+	f.Add("añb\xff\x00", []byte{2, 3, 3, 4, 250, 40, 16, 8})
+	f.Add("code and a link", []byte{0, 15, 4, 0, 5, 15, 1, 0, 10, 10, 2, 0})
+
+	f.Fuzz(func(t *testing.T, content string, data []byte) {
+		rich := text.Rich{
+			Content:  content,
+			Segments: fuzzSegments(data),
+		}
+
+		// Keep a copy to check that the input isn't modified.
+		segments := append([]text.Segment(nil), rich.Segments...)
+
+		var cfg RenderConfig
+		cfg.AnchorColor.bool = true
+		cfg.AnchorColor.uint32 = 0xFFFFFFFF
+
+		output := RenderCmplxWithConfig(rich, cfg)
+
+		if err := validateMarkup(output.Markup); err != nil {
+			t.Fatalf("invalid markup %q: %v", output.Markup, err)
+		}
+
+		if !reflect.DeepEqual(segments, rich.Segments) {
+			t.Fatal("segments were modified")
+		}
+
+		for _, block := range SplitBlocks(rich) {
+			if block.Kind != CodeBlock {
+				continue
+			}
+
+			code := RenderCodeblock(block.Content.Content, block.Language)
+			if err := validateMarkup(code); err != nil {
+				t.Fatalf("invalid code block markup %q: %v", code, err)
+			}
+		}
+	})
+}
+
+// fuzzSegments decodes segments from groups of 4 bytes: the signed start and
+// end, the kinds of segment and the attributes.
+func fuzzSegments(data []byte) []text.Segment {
+	var segments []text.Segment
+
+	for ; len(data) >= 4; data = data[4:] {
+		segment := testSegment{
+			start: int(int8(data[0])),
+			end:   int(int8(data[1])),
+			attr:  text.Attribute(data[3]) & (text.AttributeDimmed<<1 - 1),
+		}
+
+		kinds := data[2]
+		if kinds&1 != 0 {
+			segment.link = "https://example.com/?a=1&b=<2>"
+		}
+		if kinds&2 != 0 {
+			segment.image = "https://example.com/a.png"
+		}
+		if kinds&4 != 0 {
+			segment.code = lang("go")
+		}
+		if kinds&8 != 0 {
+			segment.quote = true
+		}
+		if kinds&16 != 0 {
+			segment.mention = true
+		}
+		if kinds&32 != 0 {
+			segment.color = 0xFF000080
+		}
+
+		segments = append(segments, segment)
+	}
+
+	return segments
+}
+
+// validateMarkup returns an error if the markup can't be set into a label,
+// which is if it's not well-formed Pango markup or if it has nested anchors.
+func validateMarkup(markup string) error {
+	if !utf8.ValidString(markup) {
+		return fmt.Errorf("invalid UTF-8")
+	}
+	if strings.IndexByte(markup, 0) != -1 {
+		return fmt.Errorf("null byte")
+	}
+
+	// GMarkup allows characters that XML doesn't, so replace them.
+	markup = strings.Map(func(r rune) rune {
+		if (r < 0x20 && r != '\t' && r != '\n' && r != '\r') || r == 0xFFFE || r == 0xFFFF {
+			return ' '
+		}
+		return r
+	}, markup)
+
+	d := xml.NewDecoder(strings.NewReader("<markup>" + markup + "</markup>"))
+	d.Strict = true
+
+	var anchors int
+
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := t.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "a":
+				if anchors++; anchors > 1 {
+					return fmt.Errorf("nested anchors")
+				}
+			case "markup", "span", "b", "i", "s", "u", "tt", "big", "small", "sub", "sup":
+			default:
+				return fmt.Errorf("unknown tag %q", t.Name.Local)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "a" {
+				anchors--
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package markup
+
+import (
+	"html"
+	"net/url"
+	"strings"
+
+	"github.com/diamondburned/cchat/text"
+)
+
+// RenderHTML renders the content into HTML that is safe to embed. Only a small
+// set of tags is used, links and images are limited to web URLs and all text
+// is escaped. Text blocks are paragraphs, quotes are blockquotes and code
+// blocks are preformatted code with a language-* class, as in CommonMark.
+func RenderHTML(content text.Rich) string {
+	return joinBlocks(SplitBlocks(content), renderHTMLBlock, htmlSeparator)
+}
+
+func renderHTMLBlock(block Block) string {
+	var rendered string
+
+	if block.Kind == CodeBlock {
+		rendered = "<pre><code"
+		if lang := htmlClassName(block.Language); lang != "" {
+			rendered += ` class="language-` + lang + `"`
+		}
+		rendered += ">" + html.EscapeString(block.Content.Content) + "</code></pre>"
+	} else {
+		rendered = "<p>" + htmlInline(block.Content) + "</p>"
+	}
+
+	return strings.Repeat("<blockquote>", block.Depth) +
+		rendered +
+		strings.Repeat("</blockquote>", block.Depth)
+}
+
+func htmlSeparator(prev, next int) string {
+	return "\n"
+}
+
+func htmlInline(content text.Rich) string {
+	var repaired, tree = inlineTree(content)
+	var r inlineRenderer
+
+	r.escape = func(start, end int) string {
+		s := html.EscapeString(repaired[start:end])
+		return strings.ReplaceAll(s, "\n", "<br>")
+	}
+
+	r.wrap = func(node *inlineNode, inner string) string {
+		if src, alt, ok := imageOf(node.segment); ok {
+			if !isWebURL(src, false) {
+				return html.EscapeString(alt)
+			}
+			return `<img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(alt) + `">`
+		}
+
+		attr := attributeOf(node.segment)
+
+		if attr.Has(text.AttributeMonospace) {
+			inner = "<code>" + inner + "</code>"
+		}
+		if attr.Has(text.AttributeBold) {
+			inner = "<strong>" + inner + "</strong>"
+		}
+		if attr.Has(text.AttributeItalics) {
+			inner = "<em>" + inner + "</em>"
+		}
+		if attr.Has(text.AttributeUnderline) {
+			inner = "<u>" + inner + "</u>"
+		}
+		if attr.Has(text.AttributeStrikethrough) {
+			inner = "<s>" + inner + "</s>"
+		}
+		if attr.Has(text.AttributeSpoiler) {
+			inner = `<span class="spoiler">` + inner + "</span>"
+		}
+
+		if colorer := node.segment.AsColorer(); colorer != nil {
+			rgb, _ := splitRGBA(colorer.Color())
+			inner = `<span style="color: #` + hexPad(rgb) + `">` + inner + "</span>"
+		}
+
+		if node.segment.AsMentioner() != nil {
+			inner = `<span class="mention">` + inner + "</span>"
+		}
+
+		if linker := node.segment.AsLinker(); linker != nil && isWebURL(linker.Link(), true) {
+			inner = `<a href="` + html.EscapeString(linker.Link()) + `">` + inner + "</a>"
+		}
+
+		return inner
+	}
+
+	return r.render(tree)
+}
+
+// isWebURL returns true if the URL is safe to link to, which excludes schemes
+// such as javascript:. E-mail addresses are only allowed if mailto is true.
+func isWebURL(link string, mailto bool) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return true
+	case "mailto":
+		return mailto
+	default:
+		return false
+	}
+}
+
+// htmlClassName returns the language with only the characters allowed in the
+// class name.
+func htmlClassName(language string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+			return r
+		case r == '-', r == '_', r == '+', r == '#', r == '.':
+			return r
+		default:
+			return -1
+		}
+	}, language)
+}
@@ -0,0 +1,20 @@
+package markup
+
+import "testing"
+
+func TestRenderHTML(t *testing.T) {
+	testRenderer(t, RenderHTML, map[string]string{
+		"bare link":        "<p><a href=\"https://example.com\">https://example.com</a></p>",
+		"bold":             "<p>a <strong>bold</strong> move</p>",
+		"codeblock":        "<p>code:</p>\n<pre><code class=\"language-go\">fmt.Println(&#34;&lt;hi&gt;&#34;)</code></pre>\n<p>done</p>",
+		"escaped":          "<p># not a &lt;heading&gt;<br>1. nor a list</p>",
+		"image":            "<p>look <img src=\"https://example.com/a.png\" alt=\"img\"></p>",
+		"inline code":      "<p>run <code>`go`</code> *now*</p>",
+		"link":             "<p>see the <a href=\"https://example.com/docs\">docs</a></p>",
+		"overlapping":      "<p><strong>one <em>two</em></strong><em> three</em></p>",
+		"plain":            "<p>hello, world</p>",
+		"quote":            "<p>said:</p>\n<blockquote><p>hi<br>there</p></blockquote>\n<p>ok</p>",
+		"quoted codeblock": "<blockquote><pre><code>x := 1</code></pre></blockquote>",
+		"unsafe link":      "<p>click</p>",
+	})
+}
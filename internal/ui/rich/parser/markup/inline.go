@@ -0,0 +1,129 @@
+package markup
+
+import (
+	"strings"
+
+	"github.com/diamondburned/cchat/text"
+)
+
+// inlineNode is a segment in a tree of properly nested segments. Segments that
+// overlap are split, so that each part is nested in another segment or not at
+// all. This is needed by formats that can't overlap tags.
+type inlineNode struct {
+	segment    text.Segment // nil for the root
+	start, end int
+	children   []*inlineNode
+}
+
+// inlineTree builds the tree of the inline segments in the content, which is
+// returned repaired along with it. Quote and code segments are skipped, since
+// they're blocks.
+func inlineTree(content text.Rich) (string, *inlineNode) {
+	var inline = text.Rich{
+		Content:  content.Content,
+		Segments: make([]text.Segment, 0, len(content.Segments)),
+	}
+
+	for _, segment := range content.Segments {
+		if segment.AsQuoteblocker() == nil && segment.AsCodeblocker() == nil {
+			inline.Segments = append(inline.Segments, segment)
+		}
+	}
+
+	repaired, pieces := normalize(inline)
+
+	root := &inlineNode{start: 0, end: len(repaired)}
+	stack := []*inlineNode{root}
+
+	for _, piece := range pieces {
+		start, end := piece.Bounds()
+		node := &inlineNode{segment: piece.Segment, start: start, end: end}
+
+		// Leave the segments that end before this one starts. The pieces are
+		// already nested, so this one is within the rest.
+		for len(stack) > 1 && start >= stack[len(stack)-1].end {
+			stack = stack[:len(stack)-1]
+		}
+
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, node)
+		stack = append(stack, node)
+	}
+
+	return repaired, root
+}
+
+func isImage(segment text.Segment) bool {
+	return segment.AsImager() != nil || segment.AsAvatarer() != nil
+}
+
+// inlineRenderer renders an inline tree into a text format.
+type inlineRenderer struct {
+	// escape escapes the content between the bounds.
+	escape func(start, end int) string
+	// wrap wraps the rendered children of a node in the node's format.
+	wrap func(node *inlineNode, inner string) string
+}
+
+func (r inlineRenderer) render(node *inlineNode) string {
+	var buf strings.Builder
+	var pos = node.start
+
+	for _, child := range node.children {
+		buf.WriteString(r.escape(pos, child.start))
+		buf.WriteString(r.render(child))
+		pos = child.end
+	}
+
+	buf.WriteString(r.escape(pos, node.end))
+
+	if node.segment == nil {
+		return buf.String()
+	}
+
+	return r.wrap(node, buf.String())
+}
+
+// imageOf returns the URL and the text of an image or avatar segment.
+func imageOf(segment text.Segment) (url, alt string, ok bool) {
+	if imager := segment.AsImager(); imager != nil {
+		return imager.Image(), imager.ImageText(), true
+	}
+	if avatarer := segment.AsAvatarer(); avatarer != nil {
+		return avatarer.Avatar(), avatarer.AvatarText(), true
+	}
+	return "", "", false
+}
+
+// attributeOf returns the attributes of the segment, or 0 if it has none.
+func attributeOf(segment text.Segment) text.Attribute {
+	if attributor := segment.AsAttributor(); attributor != nil {
+		return attributor.Attribute()
+	}
+	return 0
+}
+
+// trimSpaces splits the string into its leading spaces, the rest and its
+// trailing spaces.
+func trimSpaces(s string) (lead, body, trail string) {
+	body = strings.TrimLeft(s, " \t\n")
+	lead = s[:len(s)-len(body)]
+	trimmed := strings.TrimRight(body, " \t\n")
+	trail = body[len(trimmed):]
+	return lead, trimmed, trail
+}
+
+// joinBlocks renders the blocks and joins them with the separator, which is
+// given the depths of the blocks around it.
+func joinBlocks(blocks []Block, render func(Block) string, separator func(prev, next int) string) string {
+	var buf strings.Builder
+
+	for i, block := range blocks {
+		if i > 0 {
+			buf.WriteString(separator(blocks[i-1].Depth, block.Depth))
+		}
+		buf.WriteString(render(block))
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,238 @@
+package markup
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/diamondburned/cchat-gtk/internal/ui/config"
+	"github.com/diamondburned/cchat/text"
+	"github.com/diamondburned/cchat/utils/empty"
+)
+
+var (
+	// DetectLinks, if true, turns URLs and e-mail addresses in plain text into
+	// links.
+	DetectLinks = true
+	// LinkPatterns are the custom patterns turned into links. Each is a regular
+	// expression and a URL template separated by the last space, such as
+	// "JIRA-\d+ https://jira.example.com/browse/$0".
+	LinkPatterns []string
+
+	linkPatterns []linkPattern
+)
+
+func init() {
+	config.Register(config.Behavior, "Detect Links", config.Describe(
+		"Turn URLs and e-mail addresses that aren't links into links.",
+		config.Switch(&DetectLinks, nil),
+	))
+	config.Register(config.Behavior, "Link Patterns", config.Describe(
+		"Turn text matching a regular expression into a link. Each pattern is "+
+			"the expression and the URL separated by a space. The URL may use "+
+			"$0 for the whole match and $1 and so on for the groups, such as "+
+			`"JIRA-\d+ https://jira.example.com/browse/$0".`,
+		config.StringList(&LinkPatterns, validateLinkPattern, setLinkPatterns),
+	))
+}
+
+type linkPattern struct {
+	regex    *regexp.Regexp
+	template string
+}
+
+func parseLinkPattern(pattern string) (linkPattern, error) {
+	i := strings.LastIndexByte(pattern, ' ')
+	if i == -1 {
+		return linkPattern{}, fmt.Errorf("missing the URL after the expression")
+	}
+
+	regex, err := regexp.Compile(strings.TrimSpace(pattern[:i]))
+	if err != nil {
+		return linkPattern{}, err
+	}
+
+	return linkPattern{regex, pattern[i+1:]}, nil
+}
+
+func validateLinkPattern(pattern string) error {
+	_, err := parseLinkPattern(pattern)
+	return err
+}
+
+func setLinkPatterns(patterns []string) {
+	linkPatterns = linkPatterns[:0]
+
+	for _, pattern := range patterns {
+		// Patterns are validated before they're set.
+		if p, err := parseLinkPattern(pattern); err == nil {
+			linkPatterns = append(linkPatterns, p)
+		}
+	}
+}
+
+var (
+	urlRegex   = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+`)
+	emailRegex = regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`)
+)
+
+// Linkify returns the content with link segments added for the URLs, e-mail
+// addresses and custom patterns in the text that the backend didn't already
+// format. Text in links, mentions, references, images and code is skipped, as
+// is text that only partly overlaps other segments. The added segments are
+// after the original ones, so their indices stay the same. The content is
+// returned as-is if there's nothing to add or if DetectLinks is false. This
+// function is not thread-safe.
+func Linkify(content text.Rich) text.Rich {
+	if !DetectLinks {
+		return content
+	}
+
+	var links []linkSegment
+
+	add := func(start, end int, url string) {
+		if linkConflicts(content.Segments, links, start, end) {
+			return
+		}
+		links = append(links, linkSegment{start: start, end: end, url: url})
+	}
+
+	for _, loc := range urlRegex.FindAllStringIndex(content.Content, -1) {
+		start, end := loc[0], loc[0]+len(trimURL(content.Content[loc[0]:loc[1]]))
+		url := content.Content[start:end]
+		if !strings.Contains(url, "://") {
+			url = "https://" + url
+		}
+
+		add(start, end, url)
+	}
+
+	for _, loc := range emailRegex.FindAllStringIndex(content.Content, -1) {
+		add(loc[0], loc[1], "mailto:"+content.Content[loc[0]:loc[1]])
+	}
+
+	for _, pattern := range linkPatterns {
+		for _, match := range pattern.regex.FindAllStringSubmatchIndex(content.Content, -1) {
+			if match[0] == match[1] {
+				continue
+			}
+
+			url := pattern.regex.ExpandString(nil, pattern.template, content.Content, match)
+			add(match[0], match[1], string(url))
+		}
+	}
+
+	if len(links) == 0 {
+		return content
+	}
+
+	segments := make([]text.Segment, len(content.Segments), len(content.Segments)+len(links))
+	copy(segments, content.Segments)
+
+	for _, link := range links {
+		segments = append(segments, link)
+	}
+
+	return text.Rich{
+		Content:  content.Content,
+		Segments: segments,
+	}
+}
+
+// trimURL trims the punctuation after the URL, which is usually part of the
+// sentence, and the closing brackets that aren't opened in the URL.
+func trimURL(url string) string {
+	for url != "" {
+		switch last := url[len(url)-1]; last {
+		case '.', ',', ':', ';', '!', '?', '\'', '*':
+		case ')':
+			if strings.Count(url, "(") >= strings.Count(url, ")") {
+				return url
+			}
+		case ']':
+			if strings.Count(url, "[") >= strings.Count(url, "]") {
+				return url
+			}
+		case '}':
+			if strings.Count(url, "{") >= strings.Count(url, "}") {
+				return url
+			}
+		default:
+			return url
+		}
+
+		url = url[:len(url)-1]
+	}
+
+	return url
+}
+
+// linkConflicts returns true if a link in the bounds would overlap the added
+// links, be inside a segment that can't contain links or cross the bounds of
+// any other segment.
+func linkConflicts(segments []text.Segment, links []linkSegment, start, end int) bool {
+	for _, link := range links {
+		if start < link.end && link.start < end {
+			return true
+		}
+	}
+
+	for _, segment := range segments {
+		i, j := segment.Bounds()
+
+		// Only images are empty, and they can't be inside a link.
+		if i == j {
+			if start < i && i < end {
+				return true
+			}
+			continue
+		}
+
+		if j <= start || end <= i {
+			continue
+		}
+
+		if !canContainLink(segment) {
+			return true
+		}
+
+		// The link can only be inside the segment, not cross it.
+		if start < i || j < end {
+			return true
+		}
+	}
+
+	return false
+}
+
+// canContainLink returns true if the segment only formats its text, so that a
+// link can be added inside it.
+func canContainLink(segment text.Segment) bool {
+	if segment.AsLinker() != nil ||
+		segment.AsMentioner() != nil ||
+		segment.AsMessageReferencer() != nil ||
+		segment.AsCodeblocker() != nil ||
+		segment.AsImager() != nil ||
+		segment.AsAvatarer() != nil {
+		return false
+	}
+
+	if attributor := segment.AsAttributor(); attributor != nil {
+		return !attributor.Attribute().Has(text.AttributeMonospace)
+	}
+
+	return true
+}
+
+// linkSegment is a link found in plain text.
+type linkSegment struct {
+	empty.TextSegment
+	start, end int
+	url        string
+}
+
+func (l linkSegment) Bounds() (start, end int) { return l.start, l.end }
+
+func (l linkSegment) AsLinker() text.Linker { return l }
+
+func (l linkSegment) Link() string { return l.url }
@@ -0,0 +1,97 @@
+package markup
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/diamondburned/cchat/text"
+)
+
+func TestLinkify(t *testing.T) {
+	type link struct {
+		start, end int
+		url        string
+	}
+
+	setLinkPatterns([]string{`JIRA-(\d+) https://jira.example.com/browse/JIRA-$1`})
+	defer setLinkPatterns(nil)
+
+	var tests = []struct {
+		name     string
+		content  string
+		segments []text.Segment
+		links    []link
+	}{{
+		name:    "url",
+		content: "see https://example.com/a?b=c.",
+		links:   []link{{4, 29, "https://example.com/a?b=c"}},
+	}, {
+		name:    "www",
+		content: "(www.example.com)",
+		links:   []link{{1, 16, "https://www.example.com"}},
+	}, {
+		name:    "balanced parentheses",
+		content: "https://en.wikipedia.org/wiki/Go_(language)",
+		links:   []link{{0, 43, "https://en.wikipedia.org/wiki/Go_(language)"}},
+	}, {
+		name:    "email",
+		content: "mail me@example.com!",
+		links:   []link{{5, 19, "mailto:me@example.com"}},
+	}, {
+		name:    "pattern",
+		content: "fixed in JIRA-1234",
+		links:   []link{{9, 18, "https://jira.example.com/browse/JIRA-1234"}},
+	}, {
+		name:    "url containing pattern",
+		content: "https://example.com/JIRA-1",
+		links:   []link{{0, 26, "https://example.com/JIRA-1"}},
+	}, {
+		name:     "existing link",
+		content:  "https://example.com",
+		segments: []text.Segment{testSegment{start: 0, end: 19, link: "https://example.com"}},
+	}, {
+		name:     "code",
+		content:  "run https://example.com",
+		segments: []text.Segment{testSegment{start: 4, end: 23, attr: text.AttributeMonospace}},
+	}, {
+		name:     "crossing",
+		content:  "https://example.com",
+		segments: []text.Segment{testSegment{start: 3, end: 23, attr: text.AttributeBold}},
+	}, {
+		name:     "inside formatting",
+		content:  "**https://example.com**",
+		segments: []text.Segment{testSegment{start: 0, end: 23, attr: text.AttributeBold}},
+		links:    []link{{2, 21, "https://example.com"}},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			content := text.Rich{Content: test.content, Segments: test.segments}
+			output := Linkify(content)
+
+			if output.Content != content.Content {
+				t.Errorf("unexpected content %q", output.Content)
+			}
+
+			for i, segment := range test.segments {
+				if output.Segments[i] != segment {
+					t.Errorf("segment %d changed", i)
+				}
+			}
+
+			var got = []link{}
+			for _, segment := range output.Segments[len(test.segments):] {
+				start, end := segment.Bounds()
+				got = append(got, link{start, end, segment.AsLinker().Link()})
+			}
+
+			if test.links == nil {
+				test.links = []link{}
+			}
+
+			if !reflect.DeepEqual(got, test.links) {
+				t.Errorf("unexpected links %v", got)
+			}
+		})
+	}
+}
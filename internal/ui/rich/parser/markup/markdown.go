@@ -0,0 +1,256 @@
+package markup
+
+import (
+	"strings"
+
+	"github.com/diamondburned/cchat/text"
+)
+
+// markdownEscaper escapes the characters that are special anywhere in a line.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `~`, `\~`, `|`, `\|`,
+	`[`, `\[`, `]`, `\]`, `<`, `\<`, `>`, `\>`,
+)
+
+// RenderMarkdown renders the content into CommonMark. Formatting that
+// CommonMark doesn't have, such as colors and underlines, is dropped, except
+// for strikethroughs and spoilers, which use the common ~~ and || extensions.
+func RenderMarkdown(content text.Rich) string {
+	return joinBlocks(SplitBlocks(content), renderMarkdownBlock, markdownSeparator)
+}
+
+func renderMarkdownBlock(block Block) string {
+	var rendered string
+
+	if block.Kind == CodeBlock {
+		rendered = markdownCodeblock(block.Content.Content, block.Language)
+	} else {
+		rendered = markdownHardBreaks(markdownInline(block.Content))
+	}
+
+	if block.Depth == 0 {
+		return rendered
+	}
+
+	prefix := strings.Repeat("> ", block.Depth)
+	lines := strings.Split(rendered, "\n")
+
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = strings.TrimRight(prefix, " ")
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// markdownSeparator separates blocks with a blank line within the outermost
+// quote of the two, so that the paragraph of the previous block doesn't
+// continue into the next.
+func markdownSeparator(prev, next int) string {
+	depth := prev
+	if next < depth {
+		depth = next
+	}
+
+	return "\n" + strings.TrimRight(strings.Repeat("> ", depth), " ") + "\n"
+}
+
+func markdownInline(content text.Rich) string {
+	var repaired, tree = inlineTree(content)
+	var r inlineRenderer
+
+	r.escape = func(start, end int) string {
+		return markdownEscape(repaired, start, end)
+	}
+
+	r.wrap = func(node *inlineNode, inner string) string {
+		if url, alt, ok := imageOf(node.segment); ok {
+			return "![" + markdownEscaper.Replace(alt) + "](" + markdownURL(url) + ")"
+		}
+
+		attr := attributeOf(node.segment)
+
+		// Code spans show their content as-is, so nothing inside is formatted.
+		if attr.Has(text.AttributeMonospace) {
+			inner = markdownCodeSpan(repaired[node.start:node.end])
+		}
+
+		if linker := node.segment.AsLinker(); linker != nil {
+			link := linker.Link()
+			if link == repaired[node.start:node.end] && !strings.ContainsAny(link, " <>\n") {
+				inner = "<" + link + ">"
+			} else {
+				inner = "[" + inner + "](" + markdownURL(link) + ")"
+			}
+		}
+
+		var marker string
+		if attr.Has(text.AttributeItalics) {
+			marker += "*"
+		}
+		if attr.Has(text.AttributeBold) {
+			marker += "**"
+		}
+		if attr.Has(text.AttributeStrikethrough) {
+			marker += "~~"
+		}
+		if attr.Has(text.AttributeSpoiler) {
+			marker += "||"
+		}
+
+		if marker == "" {
+			return inner
+		}
+
+		// Emphasis can't start or end with spaces, so move them outside.
+		lead, body, trail := trimSpaces(inner)
+		if body == "" {
+			return inner
+		}
+
+		return lead + marker + body + reverse(marker) + trail
+	}
+
+	return r.render(tree)
+}
+
+// markdownEscape escapes the content between the bounds. Characters that are
+// only special at the start of a line are escaped if they're there.
+func markdownEscape(content string, start, end int) string {
+	var buf strings.Builder
+	buf.Grow(end - start)
+
+	for start < end {
+		lineEnd := strings.IndexByte(content[start:end], '\n')
+		if lineEnd == -1 {
+			lineEnd = end
+		} else {
+			lineEnd += start + 1
+		}
+
+		line := markdownEscaper.Replace(content[start:lineEnd])
+
+		if start == 0 || content[start-1] == '\n' {
+			line = markdownEscapeLineStart(line)
+		}
+
+		buf.WriteString(line)
+		start = lineEnd
+	}
+
+	return buf.String()
+}
+
+// markdownEscapeLineStart escapes the headings, thematic breaks and list items
+// at the start of the line.
+func markdownEscapeLineStart(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	indent := line[:len(line)-len(trimmed)]
+
+	switch {
+	case trimmed == "":
+		return line
+	case strings.IndexByte("#+-=", trimmed[0]) != -1:
+		return indent + `\` + trimmed
+	}
+
+	// Ordered list items are digits followed by a period or a parenthesis.
+	digits := strings.TrimLeft(trimmed, "0123456789")
+	if len(digits) < len(trimmed) && digits != "" && (digits[0] == '.' || digits[0] == ')') {
+		n := len(trimmed) - len(digits)
+		return indent + trimmed[:n] + `\` + digits
+	}
+
+	return line
+}
+
+// markdownHardBreaks turns the new lines between two lines of text into hard
+// line breaks, since single new lines are otherwise joined into one line.
+func markdownHardBreaks(s string) string {
+	lines := strings.Split(s, "\n")
+
+	for i := 0; i < len(lines)-1; i++ {
+		if lines[i] != "" && lines[i+1] != "" {
+			lines[i] += `\`
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// markdownCodeSpan wraps the code in enough backticks to contain any backticks
+// in it.
+func markdownCodeSpan(code string) string {
+	if code == "" {
+		return ""
+	}
+
+	fence := strings.Repeat("`", longestRun(code, '`')+1)
+
+	// Pad the code if it would otherwise merge with the fence. The padding is
+	// stripped by CommonMark.
+	if code[0] == '`' || code[len(code)-1] == '`' || code[0] == ' ' && code[len(code)-1] == ' ' {
+		code = " " + code + " "
+	}
+
+	return fence + code + fence
+}
+
+// markdownCodeblock fences the code block with enough backticks to contain any
+// backticks in it.
+func markdownCodeblock(code, language string) string {
+	fence := "```"
+	if n := longestRun(code, '`'); n >= len(fence) {
+		fence = strings.Repeat("`", n+1)
+	}
+
+	// The info string can't have backticks, and only its first word is used as
+	// the language.
+	if fields := strings.Fields(strings.ReplaceAll(language, "`", "")); len(fields) > 0 {
+		language = fields[0]
+	} else {
+		language = ""
+	}
+
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+// markdownURL escapes the characters that would end the link destination.
+func markdownURL(url string) string {
+	return markdownURLEscaper.Replace(url)
+}
+
+var markdownURLEscaper = strings.NewReplacer(
+	" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E", "\n", "%0A",
+)
+
+// longestRun returns the length of the longest run of the byte in the string.
+func longestRun(s string, b byte) int {
+	var longest, current int
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != b {
+			current = 0
+			continue
+		}
+
+		current++
+		if current > longest {
+			longest = current
+		}
+	}
+
+	return longest
+}
+
+// reverse reverses the ASCII string.
+func reverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
@@ -0,0 +1,20 @@
+package markup
+
+import "testing"
+
+func TestRenderMarkdown(t *testing.T) {
+	testRenderer(t, RenderMarkdown, map[string]string{
+		"bare link":        "<https://example.com>",
+		"bold":             "a **bold** move",
+		"codeblock":        "code:\n\n```go\nfmt.Println(\"<hi>\")\n```\n\ndone",
+		"escaped":          "\\# not a \\<heading\\>\\\n1\\. nor a list",
+		"image":            "look ![img](https://example.com/a.png)",
+		"inline code":      "run `` `go` `` \\*now\\*",
+		"link":             "see the [docs](https://example.com/docs)",
+		"overlapping":      "**one *two*** *three*",
+		"plain":            "hello, world",
+		"quote":            "said:\n\n> hi\\\n> there\n\nok",
+		"quoted codeblock": "> ```\n> x := 1\n> ```",
+		"unsafe link":      "[click](javascript:alert%281%29)",
+	})
+}
@@ -6,11 +6,11 @@ import (
 	"html"
 	"log"
 	"net/url"
-	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/diamondburned/cchat-gtk/internal/gts"
+	"github.com/diamondburned/cchat-gtk/internal/ui/config"
 	"github.com/diamondburned/cchat-gtk/internal/ui/rich/parser/attrmap"
 	"github.com/diamondburned/cchat-gtk/internal/ui/rich/parser/hl"
 	"github.com/diamondburned/cchat/text"
@@ -21,6 +21,17 @@ import (
 // Hyphenate controls whether or not texts should have hyphens on wrap.
 var Hyphenate = false
 
+// RevealSpoilers, if true, renders spoilers as regular text instead of hiding
+// them until clicked.
+var RevealSpoilers = false
+
+func init() {
+	config.Register(config.Appearance, "Always Reveal Spoilers", config.Describe(
+		"Show spoilers without having to click on them.",
+		config.Switch(&RevealSpoilers, nil),
+	))
+}
+
 func hyphenate(text string) string {
 	if !Hyphenate {
 		return text
@@ -54,6 +65,7 @@ type RenderOutput struct {
 	Input      string // useless to keep parts, as Go will keep all alive anyway
 	Mentions   []MentionSegment
 	References []ReferenceSegment
+	Spoilers   []SpoilerSegment
 }
 
 // MentionSegment is a type that satisfies both Segment and Mentioner.
@@ -68,9 +80,17 @@ type ReferenceSegment struct {
 	text.MessageReferencer
 }
 
+// SpoilerSegment is a hidden spoiler. Index is the index of the segment in the
+// rendered content's segments, which RevealSpoiler takes.
+type SpoilerSegment struct {
+	text.Segment
+	Index int
+}
+
 const (
 	MentionType   = "mention"
 	ReferenceType = "reference"
+	SpoilerType   = "spoiler"
 )
 
 func fmtSegmentURI(stype string, ix int) string {
@@ -98,6 +118,8 @@ func (r RenderOutput) URISegment(uri string) text.Segment {
 		return r.Mentions[i]
 	case ReferenceType:
 		return r.References[i]
+	case SpoilerType:
+		return r.Spoilers[i]
 	default:
 		panic("Unknown internal URI ID: " + u.Host)
 	}
@@ -106,6 +128,8 @@ func (r RenderOutput) URISegment(uri string) text.Segment {
 var simpleConfig = RenderConfig{
 	NoMentionLinks: true,
 	NoReferencing:  true,
+	NoSpoilerLinks: true,
+	NoLinkify:      true,
 }
 
 func Render(content text.Rich) string {
@@ -125,10 +149,18 @@ type RenderConfig struct {
 	// mentions.
 	NoReferencing bool
 
+	// NoSpoilerLinks, if true, will not make hidden spoilers clickable. This
+	// is used for labels that can't reveal them.
+	NoSpoilerLinks bool
+
 	// SkipImages skips rendering any image markup. This is useful for widgets
 	// that already render an outside image.
 	SkipImages bool
 
+	// NoLinkify, if true, will not turn URLs and e-mail addresses in plain
+	// text into links. See Linkify.
+	NoLinkify bool
+
 	// AnchorColor forces all anchors to be of a certain color. This is used if
 	// the boolean is true. Else, all mention links will not work and regular
 	// links will be of the default color.
@@ -168,62 +200,101 @@ func (c *RenderConfig) ensureAnchorColor() {
 }
 
 func RenderCmplxWithConfig(content text.Rich, cfg RenderConfig) RenderOutput {
+	if !cfg.NoLinkify {
+		content = Linkify(content)
+	}
+
+	// Repair the content and the segments sent by the backend, which may have
+	// bounds that would otherwise produce invalid markup. The pieces are sorted
+	// with the outer ones first, so that the tags are nested.
+	input, pieces := normalize(content)
+
 	// Fast path.
-	if len(content.Segments) == 0 {
+	if len(pieces) == 0 {
 		return RenderOutput{
-			Markup: hyphenate(html.EscapeString(content.Content)),
-			Input:  content.Content,
+			Markup: hyphenate(html.EscapeString(input)),
+			Input:  input,
 		}
 	}
 
 	buf := bytes.Buffer{}
-	buf.Grow(len(content.Content))
-
-	// Sort so that all ending points are sorted decrementally. We probably
-	// don't need SliceStable here, as we're sorting again.
-	sort.Slice(content.Segments, func(i, j int) bool {
-		_, i = content.Segments[i].Bounds()
-		_, j = content.Segments[j].Bounds()
-		return i > j
-	})
-
-	// Sort so that all starting points are sorted incrementally.
-	sort.SliceStable(content.Segments, func(i, j int) bool {
-		i, _ = content.Segments[i].Bounds()
-		j, _ = content.Segments[j].Bounds()
-		return i < j
-	})
+	buf.Grow(len(input))
 
 	// map to append strings to indices
 	var appended = attrmap.NewAppendedMap()
 
-	// map to store mentions and references
+	// map to store mentions, references and hidden spoilers
 	var mentions []MentionSegment
 	var references []ReferenceSegment
+	var spoilers []SpoilerSegment
+
+	// anchors contains the bounds of the inserted anchors, which can't be
+	// nested, and of the highlighted code blocks.
+	var anchors [][2]int
 
 	// Parse all segments.
-	for _, segment := range content.Segments {
+	for _, piece := range pieces {
+		segment := piece.Segment
 		start, end := segment.Bounds()
 
 		// hasAnchor is used to determine if the current segment has inserted
 		// any anchor tags; it is used for AnchorColor.
 		var hasAnchor bool
 
-		if linker := segment.AsLinker(); linker != nil {
-			appended.Anchor(start, end, linker.Link())
-			hasAnchor = true
-		}
+		// concealed is true if the segment is inside a hidden spoiler. These
+		// segments don't insert anchors, since the spoiler is already one and
+		// anchors can't be nested, nor colors, which would reveal the text.
+		var concealed = insideSpoiler(spoilers, start, end)
+
+		// inAnchor is true if the segment is inside an anchor or a code block
+		// from another segment, so it can't insert its own anchors.
+		var inAnchor = insideAnchor(anchors, start, end)
+
+		// Only inline images if start == end per specification. Images are
+		// anchors as well, so only their text is shown inside other anchors.
+		// Empty segments have nothing else to format.
+		if start == end {
+			// Keep mentioned avatars, which can still show their popovers.
+			if mentioner := segment.AsMentioner(); mentioner != nil {
+				mentions = append(mentions, MentionSegment{
+					Segment:   segment,
+					Mentioner: mentioner,
+				})
+			}
+
+			if cfg.SkipImages {
+				continue
+			}
 
-		// Only inline images if start == end per specification.
-		if !cfg.SkipImages && start == end {
 			if imager := segment.AsImager(); imager != nil {
-				appended.Open(start, composeImageMarkup(imager))
+				if inAnchor {
+					appended.Open(start, html.EscapeString(imager.ImageText()))
+				} else {
+					appended.Open(start, composeImageMarkup(imager))
+				}
 			}
 
 			if avatarer := segment.AsAvatarer(); avatarer != nil {
 				// Ends don't matter with images.
-				appended.Open(start, composeAvatarMarkup(avatarer))
+				if inAnchor {
+					appended.Open(start, html.EscapeString(avatarer.AvatarText()))
+				} else {
+					appended.Open(start, composeAvatarMarkup(avatarer))
+				}
 			}
+
+			continue
+		}
+
+		// hidden is true if the segment is a spoiler that's hidden. Its own
+		// links, mentions and references aren't clickable until it's revealed,
+		// since clicking it should reveal it instead.
+		var hidden = isHiddenSpoiler(segment) && !concealed
+
+		if linker := segment.AsLinker(); linker != nil && !hidden && !concealed && !inAnchor {
+			appended.Anchor(start, end, linker.Link())
+			anchors = append(anchors, [2]int{start, end})
+			hasAnchor = true
 		}
 
 		// Mentioner needs to be before colorer, as we'd want the below color
@@ -232,8 +303,9 @@ func RenderCmplxWithConfig(content text.Rich, cfg RenderConfig) RenderOutput {
 		if mentioner := segment.AsMentioner(); mentioner != nil {
 			// Render the mention into "cchat://mention:0" or such. Other
 			// components will take care of showing the information.
-			if !cfg.NoMentionLinks {
+			if !cfg.NoMentionLinks && !hidden && !concealed && !hasAnchor && !inAnchor {
 				appended.AnchorNU(start, end, fmtSegmentURI(MentionType, len(mentions)))
+				anchors = append(anchors, [2]int{start, end})
 				hasAnchor = true
 			}
 
@@ -244,7 +316,7 @@ func RenderCmplxWithConfig(content text.Rich, cfg RenderConfig) RenderOutput {
 			})
 		}
 
-		if colorer := segment.AsColorer(); colorer != nil {
+		if colorer := segment.AsColorer(); colorer != nil && !concealed {
 			appended.Span(start, end, colorAttrs(colorer.Color(), false)...)
 		} else if hasAnchor {
 			cfg.ensureAnchorColor()
@@ -255,10 +327,11 @@ func RenderCmplxWithConfig(content text.Rich, cfg RenderConfig) RenderOutput {
 		// borrowing the anchor tag for its use. We should also prefer the
 		// username popover (Mention) over this.
 		if reference := segment.AsMessageReferencer(); reference != nil {
-			if !cfg.NoReferencing && !hasAnchor {
+			if !cfg.NoReferencing && !hidden && !hasAnchor && !concealed && !inAnchor {
 				// Render the mention into "cchat://reference:0" or such. Other
 				// components will take care of showing the information.
 				appended.AnchorNU(start, end, fmtSegmentURI(ReferenceType, len(references)))
+				anchors = append(anchors, [2]int{start, end})
 			}
 
 			// Add the mention segment into the list regardless of hyperlinks.
@@ -269,49 +342,172 @@ func RenderCmplxWithConfig(content text.Rich, cfg RenderConfig) RenderOutput {
 		}
 
 		if attributor := segment.AsAttributor(); attributor != nil {
-			appended.Span(start, end, markupAttr(attributor.Attribute()))
+			attr := attributor.Attribute()
+
+			// Hide the spoiler behind a block of solid color, unless the user
+			// wants them revealed. It's only clickable if it's not already in
+			// another anchor.
+			if hidden {
+				color := cfg.spoilerColor()
+
+				if !cfg.NoSpoilerLinks && !inAnchor {
+					appended.AnchorNU(start, end, fmtSegmentURI(SpoilerType, len(spoilers)))
+					anchors = append(anchors, [2]int{start, end})
+				}
+				appended.Span(start, end,
+					wrapKeyValue("color", color), wrapKeyValue("bgcolor", color))
+
+				spoilers = append(spoilers, SpoilerSegment{
+					Segment: segment,
+					Index:   piece.index,
+				})
+			}
+
+			appended.Span(start, end, markupAttr(attr))
 		}
 
-		if codeblocker := segment.AsCodeblocker(); codeblocker != nil {
-			start, end := segment.Bounds()
+		if codeblocker := segment.AsCodeblocker(); codeblocker != nil && !concealed {
 			// Syntax highlight the codeblock.
 			hl.Segments(
 				&appended,
-				content.Content,
+				input,
 				start, end,
 				codeblocker.CodeblockLanguage(),
 			)
+
+			// The highlighting spans may cross the segments inside the code,
+			// which would mis-nest any anchors in there.
+			anchors = append(anchors, [2]int{start, end})
 		}
 
-		// TODO: make this not shit. Maybe make it somehow not rely on green
-		// arrows. Or maybe.
+		// Quotes are split out with SplitBlocks where possible. Labels that
+		// show everything at once only dim them.
 		if segment.AsQuoteblocker() != nil {
-			appended.Span(start, end, `color="#789922"`)
+			appended.Span(start, end, `alpha="70%"`)
 		}
 	}
 
+	writeMarkup(&buf, input, &appended)
+
+	return RenderOutput{
+		Markup:     hyphenate(buf.String()),
+		Input:      input,
+		Mentions:   mentions,
+		References: references,
+		Spoilers:   spoilers,
+	}
+}
+
+// RenderCodeblock renders the code into syntax highlighted markup. The language
+// is guessed from the code if it's empty. Invalid UTF-8 and null bytes in the
+// code are replaced.
+func RenderCodeblock(code, language string) string {
+	code, _ = repairContent(code)
+
+	buf := bytes.Buffer{}
+	buf.Grow(len(code))
+
+	var appended = attrmap.NewAppendedMap()
+	hl.Segments(&appended, code, 0, len(code), language)
+
+	writeMarkup(&buf, code, &appended)
+	return buf.String()
+}
+
+// writeMarkup writes the escaped content with the tags in the map inserted.
+func writeMarkup(buf *bytes.Buffer, content string, appended *attrmap.AppendMap) {
 	var lastIndex = 0
 
-	for _, index := range appended.Finalize(len(content.Content)) {
-		// Prevent faulty backend with erroneous insertions.
-		if index > len(content.Content) {
-			index = len(content.Content)
+	for _, index := range appended.Finalize(len(content)) {
+		// Prevent faulty insertions past the end from dropping their tags.
+		end := index
+		if end > len(content) {
+			end = len(content)
 		}
 
 		// Write the content.
-		buf.WriteString(html.EscapeString(content.Content[lastIndex:index]))
+		buf.WriteString(html.EscapeString(content[lastIndex:end]))
 		// Write the tags.
 		buf.WriteString(appended.Get(index))
 		// Set the last index.
-		lastIndex = index
+		lastIndex = end
 	}
+}
 
-	return RenderOutput{
-		Markup:     hyphenate(buf.String()),
-		Input:      content.Content,
-		Mentions:   mentions,
-		References: references,
+// insideSpoiler returns true if the given bounds are within any of the hidden
+// spoilers.
+func insideSpoiler(spoilers []SpoilerSegment, start, end int) bool {
+	for _, spoiler := range spoilers {
+		i, j := spoiler.Bounds()
+		if i <= start && end <= j {
+			return true
+		}
+	}
+	return false
+}
+
+// insideAnchor returns true if the given bounds are within any of the anchors.
+// Empty bounds at the end of an anchor are after it.
+func insideAnchor(anchors [][2]int, start, end int) bool {
+	for _, anchor := range anchors {
+		if anchor[0] <= start && end <= anchor[1] && start < anchor[1] {
+			return true
+		}
 	}
+	return false
+}
+
+// spoilerColor returns the color of a hidden spoiler, which is the foreground
+// color, so that the text is unreadable.
+func (c *RenderConfig) spoilerColor() string {
+	c.ensureAnchorColor()
+
+	if !c.AnchorColor.bool {
+		return "#808080"
+	}
+
+	rgb, _ := splitRGBA(c.AnchorColor.uint32)
+	return "#" + hexPad(rgb)
+}
+
+// RevealSpoiler returns a copy of the content with the spoiler at the given
+// segment index shown as regular text. The index is the Index of the
+// SpoilerSegment rendered from the same content.
+func RevealSpoiler(content text.Rich, index int) text.Rich {
+	if index < 0 || index >= len(content.Segments) {
+		return content
+	}
+
+	segments := make([]text.Segment, len(content.Segments))
+	copy(segments, content.Segments)
+	segments[index] = revealedSpoiler{segments[index]}
+
+	return text.Rich{
+		Content:  content.Content,
+		Segments: segments,
+	}
+}
+
+// isHiddenSpoiler returns true if the segment is a spoiler that should be
+// hidden.
+func isHiddenSpoiler(segment text.Segment) bool {
+	if RevealSpoilers {
+		return false
+	}
+
+	attributor := segment.AsAttributor()
+	return attributor != nil && attributor.Attribute().Has(text.AttributeSpoiler)
+}
+
+// revealedSpoiler wraps a spoiler segment to remove its spoiler attribute.
+type revealedSpoiler struct {
+	text.Segment
+}
+
+func (s revealedSpoiler) AsAttributor() text.Attributor { return s }
+
+func (s revealedSpoiler) Attribute() text.Attribute {
+	return s.Segment.AsAttributor().Attribute() &^ text.AttributeSpoiler
 }
 
 // splitRGBA splits the given rgba integer into rgb and a.
@@ -456,9 +652,6 @@ func markupAttr(attr text.Attribute) string {
 	if attr.Has(text.AttributeStrikethrough) {
 		attrs = append(attrs, `strikethrough="true"`)
 	}
-	if attr.Has(text.AttributeSpoiler) {
-		attrs = append(attrs, `alpha="35%"`) // no fancy click here
-	}
 	if attr.Has(text.AttributeMonospace) {
 		attrs = append(attrs, `font_family="monospace"`)
 	}
@@ -1,24 +1,52 @@
 package markup
 
 import (
+	"strings"
 	"testing"
 
-	"github.com/diamondburned/cchat-mock/segments"
 	"github.com/diamondburned/cchat/text"
+	"github.com/diamondburned/cchat/utils/empty"
 )
 
 func TestRenderMarkup(t *testing.T) {
 	content := text.Rich{Content: "astolfo is the best trap"}
 	content.Segments = []text.Segment{
-		segments.NewColored(content.Content, 0x55CDFC),
+		coloredSegment{0, len(content.Content), text.SolidColor(0x55CDFC)},
 	}
-	expect := `<span color="#55CDFC">` + content.Content + "</span>"
+	expect := `<span color="#55cdfc">` + content.Content + "</span>"
 
-	if text := RenderMarkup(content); text != expect {
+	if text := Render(content); text != expect {
 		t.Fatal("Unexpected text:", text)
 	}
 }
 
+func TestRenderSpoilerLink(t *testing.T) {
+	content := text.Rich{Content: "secret"}
+	content.Segments = []text.Segment{testSegment{
+		start: 0,
+		end:   6,
+		attr:  text.AttributeSpoiler,
+		link:  "https://example.com",
+	}}
+
+	var cfg RenderConfig
+	cfg.AnchorColor.bool = true
+	cfg.AnchorColor.uint32 = text.SolidColor(0x808080)
+
+	hidden := RenderCmplxWithConfig(content, cfg)
+	if strings.Contains(hidden.Markup, "example.com") {
+		t.Fatal("Hidden spoiler has a link:", hidden.Markup)
+	}
+	if !strings.Contains(hidden.Markup, `bgcolor="#808080"`) || len(hidden.Spoilers) != 1 {
+		t.Fatal("Spoiler isn't hidden:", hidden.Markup)
+	}
+
+	revealed := RenderCmplxWithConfig(RevealSpoiler(content, hidden.Spoilers[0].Index), cfg)
+	if !strings.Contains(revealed.Markup, `href="https://example.com"`) {
+		t.Fatal("Revealed spoiler has no link:", revealed.Markup)
+	}
+}
+
 // Test no longer works, and should not work anyway.
 
 // func TestRenderMarkupPartial(t *testing.T) {
@@ -46,7 +74,7 @@ type coloredSegment struct {
 	color uint32
 }
 
-var _ text.Colorer = (*coloredSegment)(nil)
+var _ text.Segment = (*coloredSegment)(nil)
 
 func (c coloredSegment) Bounds() (start, end int) {
 	return c.start, c.end
@@ -55,3 +83,171 @@ func (c coloredSegment) Bounds() (start, end int) {
 func (c coloredSegment) Color() uint32 {
 	return c.color
 }
+
+func (c coloredSegment) AsColorer() text.Colorer { return c }
+
+// Segment asserters that coloredSegment doesn't implement.
+func (coloredSegment) AsLinker() text.Linker                       { return nil }
+func (coloredSegment) AsImager() text.Imager                       { return nil }
+func (coloredSegment) AsAvatarer() text.Avatarer                   { return nil }
+func (coloredSegment) AsMentioner() text.Mentioner                 { return nil }
+func (coloredSegment) AsAttributor() text.Attributor               { return nil }
+func (coloredSegment) AsCodeblocker() text.Codeblocker             { return nil }
+func (coloredSegment) AsQuoteblocker() text.Quoteblocker           { return nil }
+func (coloredSegment) AsMessageReferencer() text.MessageReferencer { return nil }
+
+// testSegment is a segment that implements the asserters of its set fields.
+type testSegment struct {
+	empty.TextSegment
+	start, end int
+
+	link    string
+	attr    text.Attribute
+	image   string // image URL; the text is "img"
+	code    *string
+	quote   bool
+	mention bool
+	color   uint32 // RGBA; 0 for none
+}
+
+var _ text.Segment = (*testSegment)(nil)
+
+func (s testSegment) Bounds() (start, end int) { return s.start, s.end }
+
+func (s testSegment) AsLinker() text.Linker {
+	if s.link == "" {
+		return nil
+	}
+	return s
+}
+
+func (s testSegment) Link() string { return s.link }
+
+func (s testSegment) AsAttributor() text.Attributor {
+	if s.attr == 0 {
+		return nil
+	}
+	return s
+}
+
+func (s testSegment) Attribute() text.Attribute { return s.attr }
+
+func (s testSegment) AsImager() text.Imager {
+	if s.image == "" {
+		return nil
+	}
+	return s
+}
+
+func (s testSegment) Image() string         { return s.image }
+func (s testSegment) ImageSize() (w, h int) { return 0, 0 }
+func (s testSegment) ImageText() string     { return "img" }
+func (s testSegment) AsCodeblocker() text.Codeblocker {
+	if s.code == nil {
+		return nil
+	}
+	return s
+}
+
+func (s testSegment) CodeblockLanguage() string { return *s.code }
+
+func (s testSegment) AsQuoteblocker() text.Quoteblocker {
+	if !s.quote {
+		return nil
+	}
+	return s
+}
+
+func (s testSegment) QuotePrefix() string { return ">" }
+
+func (s testSegment) AsMentioner() text.Mentioner {
+	if !s.mention {
+		return nil
+	}
+	return s
+}
+
+func (s testSegment) MentionInfo() text.Rich { return text.Plain("someone") }
+
+func (s testSegment) AsColorer() text.Colorer {
+	if s.color == 0 {
+		return nil
+	}
+	return s
+}
+
+func (s testSegment) Color() uint32 { return s.color }
+
+func lang(language string) *string { return &language }
+
+// rendererTests are the inputs shared by the tests of the renderers.
+var rendererTests = map[string]text.Rich{
+	"plain": {Content: "hello, world"},
+	"bold": {
+		Content:  "a bold move",
+		Segments: []text.Segment{testSegment{start: 2, end: 6, attr: text.AttributeBold}},
+	},
+	"overlapping": {
+		Content: "one two three",
+		Segments: []text.Segment{
+			testSegment{start: 0, end: 7, attr: text.AttributeBold},
+			testSegment{start: 4, end: 13, attr: text.AttributeItalics},
+		},
+	},
+	"link": {
+		Content:  "see the docs",
+		Segments: []text.Segment{testSegment{start: 8, end: 12, link: "https://example.com/docs"}},
+	},
+	"bare link": {
+		Content:  "https://example.com",
+		Segments: []text.Segment{testSegment{start: 0, end: 19, link: "https://example.com"}},
+	},
+	"unsafe link": {
+		Content:  "click",
+		Segments: []text.Segment{testSegment{start: 0, end: 5, link: "javascript:alert(1)"}},
+	},
+	"image": {
+		Content:  "look ",
+		Segments: []text.Segment{testSegment{start: 5, end: 5, image: "https://example.com/a.png"}},
+	},
+	"inline code": {
+		Content:  "run `go` *now*",
+		Segments: []text.Segment{testSegment{start: 4, end: 8, attr: text.AttributeMonospace}},
+	},
+	"escaped": {
+		Content: "# not a <heading>\n1. nor a list",
+	},
+	"quote": {
+		Content:  "said:\nhi\nthere\nok",
+		Segments: []text.Segment{testSegment{start: 6, end: 15, quote: true}},
+	},
+	"codeblock": {
+		Content:  "code:\nfmt.Println(\"<hi>\")\ndone",
+		Segments: []text.Segment{testSegment{start: 6, end: 25, code: lang("go")}},
+	},
+	"quoted codeblock": {
+		Content: "x := 1",
+		Segments: []text.Segment{
+			testSegment{start: 0, end: 6, quote: true},
+			testSegment{start: 0, end: 6, code: lang("")},
+		},
+	},
+}
+
+// testRenderer runs the renderer over the shared inputs and compares them with
+// the expected outputs, which must cover all inputs.
+func testRenderer(t *testing.T, render func(text.Rich) string, expects map[string]string) {
+	for name, content := range rendererTests {
+		expect, ok := expects[name]
+		if !ok {
+			t.Errorf("missing expected output of %q", name)
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			if got := render(content); got != expect {
+				t.Errorf("unexpected output:\nexpected %q\ngot      %q", expect, got)
+			}
+		})
+	}
+}
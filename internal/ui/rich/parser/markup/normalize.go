@@ -0,0 +1,182 @@
+package markup
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/diamondburned/cchat/text"
+)
+
+// segmentPiece is a segment or a part of it with repaired bounds.
+type segmentPiece struct {
+	text.Segment
+	// index is the index of the segment in the original content.
+	index int
+}
+
+// normalize repairs the content and its segments without modifying them, so
+// that they can be rendered into valid markup. Invalid UTF-8 and null bytes in
+// the content are replaced, and the segments are moved to match.
+//
+// The bounds of the segments are swapped if they're inverted, clamped to the
+// content and widened to not cut characters in half. Segments that partly
+// overlap are split, so that every piece is either nested in another or not at
+// all. Empty segments other than images are dropped, since they can't contain
+// anything. The pieces are sorted by their starting points and then by their
+// lengths, longest first.
+func normalize(content text.Rich) (string, []segmentPiece) {
+	repaired, offset := repairContent(content.Content)
+
+	var pieces = make([]segmentPiece, 0, len(content.Segments))
+
+	for i, segment := range content.Segments {
+		start, end := segment.Bounds()
+		if start > end {
+			start, end = end, start
+		}
+
+		start = runeStart(repaired, offset(clamp(start, 0, len(content.Content))))
+		end = runeEnd(repaired, offset(clamp(end, 0, len(content.Content))))
+
+		if start == end && !isImage(segment) {
+			continue
+		}
+
+		pieces = append(pieces, segmentPiece{
+			Segment: clippedSegment{segment, start, end},
+			index:   i,
+		})
+	}
+
+	sort.SliceStable(pieces, func(i, j int) bool {
+		return pieceBefore(pieces[i], pieces[j])
+	})
+
+	return repaired, nestPieces(pieces)
+}
+
+// nestPieces splits the sorted pieces that partly overlap the ones before them.
+// The split parts are inserted back in order.
+func nestPieces(pieces []segmentPiece) []segmentPiece {
+	// stack contains the ends of the pieces that the current piece is in.
+	var stack []int
+
+	for i := 0; i < len(pieces); i++ {
+		piece := pieces[i]
+		start, end := piece.Bounds()
+
+		// Leave the pieces that end before this one starts.
+		for len(stack) > 0 && start >= stack[len(stack)-1] {
+			stack = stack[:len(stack)-1]
+		}
+
+		// Split the piece if it goes past the one it's in.
+		if len(stack) > 0 && end > stack[len(stack)-1] {
+			parentEnd := stack[len(stack)-1]
+			segment := piece.Segment.(clippedSegment).Segment
+
+			pieces[i].Segment = clippedSegment{segment, start, parentEnd}
+			end = parentEnd
+
+			rest := segmentPiece{
+				Segment: clippedSegment{segment, parentEnd, piece.end()},
+				index:   piece.index,
+			}
+
+			j := i + 1 + sort.Search(len(pieces)-i-1, func(j int) bool {
+				return pieceBefore(rest, pieces[i+1+j])
+			})
+
+			pieces = append(pieces, segmentPiece{})
+			copy(pieces[j+1:], pieces[j:])
+			pieces[j] = rest
+		}
+
+		stack = append(stack, end)
+	}
+
+	return pieces
+}
+
+func (p segmentPiece) end() int {
+	_, end := p.Bounds()
+	return end
+}
+
+// pieceBefore returns true if piece i starts before piece j, or if it's longer
+// when they start at the same position.
+func pieceBefore(i, j segmentPiece) bool {
+	istart, iend := i.Bounds()
+	jstart, jend := j.Bounds()
+
+	if istart != jstart {
+		return istart < jstart
+	}
+	return iend > jend
+}
+
+// repairContent replaces invalid UTF-8 and null bytes with the replacement
+// character, since markup must be valid UTF-8 and Gtk stops at null bytes. The
+// returned function maps the offsets of the original content to the repaired
+// one.
+func repairContent(content string) (string, func(int) int) {
+	if utf8.ValidString(content) && strings.IndexByte(content, 0) == -1 {
+		return content, func(i int) int { return i }
+	}
+
+	var buf strings.Builder
+	buf.Grow(len(content))
+
+	// offsets maps every original offset, including the end, to the new one.
+	var offsets = make([]int, len(content)+1)
+
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRuneInString(content[i:])
+		if (r == utf8.RuneError && size == 1) || r == 0 {
+			r, size = utf8.RuneError, 1
+		}
+
+		for j := 0; j < size; j++ {
+			offsets[i+j] = buf.Len() + j
+		}
+
+		if size == 1 && r == utf8.RuneError {
+			buf.WriteRune(utf8.RuneError)
+		} else {
+			buf.WriteString(content[i : i+size])
+		}
+
+		i += size
+	}
+
+	offsets[len(content)] = buf.Len()
+
+	return buf.String(), func(i int) int { return offsets[i] }
+}
+
+// runeStart moves the offset back to the start of the character it's in.
+func runeStart(s string, i int) int {
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
+
+// runeEnd moves the offset forward to the end of the character it's in.
+func runeEnd(s string, i int) int {
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i++
+	}
+	return i
+}
+
+func clamp(i, min, max int) int {
+	if i < min {
+		return min
+	}
+	if i > max {
+		return max
+	}
+	return i
+}
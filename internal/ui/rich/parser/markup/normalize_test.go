@@ -0,0 +1,83 @@
+package markup
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/diamondburned/cchat/text"
+)
+
+func TestNormalize(t *testing.T) {
+	type bounds [2]int
+
+	var tests = []struct {
+		name    string
+		content string
+		in      []bounds
+		output  string
+		out     []bounds
+	}{{
+		name:    "out of range",
+		content: "hello",
+		in:      []bounds{{-3, 2}, {3, 99}},
+		output:  "hello",
+		out:     []bounds{{0, 2}, {3, 5}},
+	}, {
+		name:    "inverted",
+		content: "hello",
+		in:      []bounds{{4, 1}},
+		output:  "hello",
+		out:     []bounds{{1, 4}},
+	}, {
+		name:    "empty",
+		content: "hello",
+		in:      []bounds{{2, 2}, {7, 9}},
+		output:  "hello",
+		out:     []bounds{},
+	}, {
+		name:    "overlapping",
+		content: "one two three",
+		in:      []bounds{{4, 13}, {0, 7}},
+		output:  "one two three",
+		out:     []bounds{{0, 7}, {4, 7}, {7, 13}},
+	}, {
+		name:    "mid character",
+		content: "añb",
+		in:      []bounds{{2, 3}},
+		output:  "añb",
+		out:     []bounds{{1, 3}},
+	}, {
+		name:    "invalid UTF-8",
+		content: "a\xffb\x00c",
+		in:      []bounds{{1, 3}, {4, 5}},
+		output:  "a�b�c",
+		out:     []bounds{{1, 5}, {8, 9}},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			content := text.Rich{Content: test.content}
+			for _, b := range test.in {
+				content.Segments = append(content.Segments, testSegment{
+					start: b[0],
+					end:   b[1],
+					attr:  text.AttributeBold,
+				})
+			}
+
+			output, pieces := normalize(content)
+			if output != test.output {
+				t.Errorf("unexpected content %q", output)
+			}
+
+			var got = make([]bounds, len(pieces))
+			for i, piece := range pieces {
+				got[i][0], got[i][1] = piece.Bounds()
+			}
+
+			if !reflect.DeepEqual(got, test.out) {
+				t.Errorf("unexpected bounds %v", got)
+			}
+		})
+	}
+}
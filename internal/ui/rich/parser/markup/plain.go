@@ -0,0 +1,63 @@
+package markup
+
+import (
+	"strings"
+
+	"github.com/diamondburned/cchat/text"
+)
+
+// RenderPlain renders the content into plain text. Images are replaced with
+// their text, links show their URLs after their text if they're different, and
+// quotes are prefixed with "> ". Code blocks are kept as-is.
+func RenderPlain(content text.Rich) string {
+	return joinBlocks(SplitBlocks(content), renderPlainBlock, plainSeparator)
+}
+
+func renderPlainBlock(block Block) string {
+	var rendered string
+
+	if block.Kind == CodeBlock {
+		rendered = block.Content.Content
+	} else {
+		rendered = plainInline(block.Content)
+	}
+
+	if block.Depth == 0 {
+		return rendered
+	}
+
+	prefix := strings.Repeat("> ", block.Depth)
+	return prefix + strings.ReplaceAll(rendered, "\n", "\n"+prefix)
+}
+
+func plainSeparator(prev, next int) string {
+	return "\n"
+}
+
+func plainInline(content text.Rich) string {
+	var repaired, tree = inlineTree(content)
+	var r inlineRenderer
+
+	r.escape = func(start, end int) string {
+		return repaired[start:end]
+	}
+
+	r.wrap = func(node *inlineNode, inner string) string {
+		if _, alt, ok := imageOf(node.segment); ok {
+			return alt
+		}
+
+		if linker := node.segment.AsLinker(); linker != nil {
+			switch link := linker.Link(); {
+			case inner == "":
+				return link
+			case link != inner && link != "":
+				inner += " (" + link + ")"
+			}
+		}
+
+		return inner
+	}
+
+	return r.render(tree)
+}
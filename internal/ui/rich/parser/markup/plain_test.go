@@ -0,0 +1,20 @@
+package markup
+
+import "testing"
+
+func TestRenderPlain(t *testing.T) {
+	testRenderer(t, RenderPlain, map[string]string{
+		"bare link":        "https://example.com",
+		"bold":             "a bold move",
+		"codeblock":        "code:\nfmt.Println(\"<hi>\")\ndone",
+		"escaped":          "# not a <heading>\n1. nor a list",
+		"image":            "look img",
+		"inline code":      "run `go` *now*",
+		"link":             "see the docs (https://example.com/docs)",
+		"overlapping":      "one two three",
+		"plain":            "hello, world",
+		"quote":            "said:\n> hi\n> there\nok",
+		"quoted codeblock": "> x := 1",
+		"unsafe link":      "click (javascript:alert(1))",
+	})
+}
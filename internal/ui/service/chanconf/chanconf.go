@@ -0,0 +1,114 @@
+// Package chanconf stores per-channel overrides of the global settings, keyed
+// by the breadcrumb path of each server like savepath.
+package chanconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/diamondburned/cchat-gtk/internal/ui/config"
+	"github.com/diamondburned/cchat-gtk/internal/ui/service/session/server/traverse"
+	"github.com/pkg/errors"
+)
+
+// map of JSON-encoded paths to overridden values.
+var channels = map[string]config.RawConfig{}
+
+const configName = "channels.json"
+
+// Sections are the config sections that can be overridden per channel.
+var Sections = []config.Section{
+	config.Appearance,
+	config.Behavior,
+	config.Notifications,
+}
+
+func init() {
+	// Apply the opened channel's overrides again if the file is changed.
+	config.RegisterConfig(configName, &channels, func() {
+		config.Override(channels[key(active)])
+	})
+}
+
+// active is the path of the opened channel.
+var active []cchat.ID
+
+func key(path []cchat.ID) string {
+	b, _ := json.Marshal(path)
+	return string(b)
+}
+
+// Get returns the overridden values of the given channel. The returned value
+// must not be modified.
+func Get(b traverse.Breadcrumber) config.RawConfig {
+	return channels[key(traverse.TryID(b))]
+}
+
+// Set replaces the overridden values of the given channel and saves them. If
+// the channel is currently opened, then the new values are applied
+// immediately. This function is not thread-safe.
+func Set(b traverse.Breadcrumber, raw config.RawConfig) {
+	var path = traverse.TryID(b)
+
+	if len(raw) == 0 {
+		delete(channels, key(path))
+	} else {
+		channels[key(path)] = raw
+	}
+
+	if key(path) == key(active) {
+		config.Override(raw)
+	}
+
+	save()
+}
+
+// Apply applies the overrides of the given channel over the global settings,
+// reverting the previous channel's. A nil breadcrumb reverts to the global
+// settings. This function is not thread-safe.
+func Apply(b traverse.Breadcrumber) {
+	active = traverse.TryID(b)
+	config.Override(channels[key(active)])
+}
+
+// saved is the generation of the latest settings written to disk. Saves run in
+// their own goroutines, so an older save that runs last must not overwrite a
+// newer one.
+var saved struct {
+	sync.Mutex
+	generation uint64
+}
+
+// generation is incremented for every save. It is only accessed in the main
+// thread.
+var generation uint64
+
+func save() {
+	var buf bytes.Buffer
+
+	// Marshal in the same thread to avoid race conditions.
+	if err := config.PrettyMarshal(&buf, channels); err != nil {
+		log.Error(errors.Wrap(err, "Failed to marshal channel settings"))
+		return
+	}
+
+	generation++
+	gen := generation
+
+	go func() {
+		saved.Lock()
+		defer saved.Unlock()
+
+		if gen < saved.generation {
+			return
+		}
+		saved.generation = gen
+
+		if err := config.SaveToFile(configName, buf.Bytes()); err != nil {
+			log.Error(errors.Wrap(err, "Failed to save channel settings"))
+		}
+	}()
+}
@@ -0,0 +1,137 @@
+package chanconf
+
+import (
+	"encoding/json"
+
+	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/diamondburned/cchat-gtk/internal/ui/config"
+	"github.com/diamondburned/cchat-gtk/internal/ui/dialog"
+	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
+	"github.com/diamondburned/cchat-gtk/internal/ui/service/session/server/traverse"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/pkg/errors"
+)
+
+type row struct {
+	section  config.Section
+	entry    config.Entry
+	override *gtk.CheckButton
+}
+
+// Spawn shows the dialog to edit the settings of the given channel.
+func Spawn(b traverse.Breadcrumber, name string) {
+	var current = Get(b)
+
+	// Override every shown entry, so that the widgets show the channel's values
+	// and editing them never touches the global values.
+	var all = config.RawConfig{}
+
+	// Start from the global values.
+	config.Override(nil)
+
+	var rows []row
+
+	grid, _ := gtk.GridNew()
+	grid.SetRowSpacing(4)
+	grid.SetColumnSpacing(8)
+	grid.SetVAlign(gtk.ALIGN_START)
+	grid.Show()
+	primitives.AddClass(grid, "config")
+
+	sections := config.Sections()
+
+	for _, section := range Sections {
+		sectName := section.String()
+		all[sectName] = map[string]json.RawMessage{}
+
+		for _, entry := range sections[section] {
+			v, ok := current[sectName][entry.Name]
+			if !ok {
+				global, err := entry.Value.MarshalJSON()
+				if err != nil {
+					log.Error(errors.Wrapf(err, "Failed to marshal key %q", entry.Name))
+					continue
+				}
+				v = global
+			}
+
+			all[sectName][entry.Name] = v
+			rows = append(rows, row{section: section, entry: entry})
+		}
+	}
+
+	config.Override(all)
+
+	for i := range rows {
+		row := &rows[i]
+		_, overridden := current[row.section.String()][row.entry.Name]
+
+		row.override, _ = gtk.CheckButtonNewWithLabel(row.entry.Name)
+		row.override.SetActive(overridden)
+		row.override.SetHExpand(true)
+		row.override.SetTooltipText(row.entry.Description)
+		row.override.Show()
+
+		value := row.entry.Value.Construct()
+		widget := value.ToWidget()
+		widget.SetSensitive(overridden)
+
+		row.override.Connect("toggled", func(check *gtk.CheckButton) {
+			widget.SetSensitive(check.GetActive())
+		})
+
+		grid.Attach(row.override, 0, i, 1, 1)
+		grid.Attach(value, 1, i, 1, 1)
+	}
+
+	hint, _ := gtk.LabelNew("Checked settings override the global preferences in this channel.")
+	hint.SetXAlign(0)
+	hint.SetLineWrap(true)
+	hint.Show()
+
+	box, _ := gtk.BoxNew(gtk.ORIENTATION_VERTICAL, 8)
+	box.SetMarginTop(8)
+	box.SetMarginBottom(8)
+	box.SetMarginStart(16)
+	box.SetMarginEnd(16)
+	box.PackStart(hint, false, false, 0)
+	box.PackStart(grid, false, false, 0)
+	box.Show()
+
+	sw, _ := gtk.ScrolledWindowNew(nil, nil)
+	sw.SetPolicy(gtk.POLICY_NEVER, gtk.POLICY_AUTOMATIC)
+	sw.Add(box)
+	sw.Show()
+
+	m := dialog.NewModal(sw, "Settings for "+name, "_Save", func(m *dialog.Modal) {
+		var raw = config.RawConfig{}
+
+		for _, row := range rows {
+			if !row.override.GetActive() {
+				continue
+			}
+
+			v, err := row.entry.Value.MarshalJSON()
+			if err != nil {
+				log.Error(errors.Wrapf(err, "Failed to marshal key %q", row.entry.Name))
+				continue
+			}
+
+			sectName := row.section.String()
+			if raw[sectName] == nil {
+				raw[sectName] = map[string]json.RawMessage{}
+			}
+			raw[sectName][row.entry.Name] = v
+		}
+
+		Set(b, raw)
+		m.Destroy()
+	})
+	m.SetDefaultSize(400, 400)
+	m.Connect("destroy", func(interface{}) {
+		// Revert the unsaved edits and bring back the opened channel's
+		// overrides.
+		config.Override(channels[key(active)])
+	})
+	m.Show()
+}
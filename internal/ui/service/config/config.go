@@ -23,8 +23,14 @@ func MenuItem(conf Configurator) menu.Item {
 	return menu.SimpleItem("Configure", func() { Spawn(conf) })
 }
 
-// Restore restores the config in the background.
+// Restore restores the config in the background. The config is restored again
+// when its file is changed on disk.
 func Restore(conf Configurator) {
+	config.OnFileChange(serviceFile(conf), func() { restore(conf) })
+	restore(conf)
+}
+
+func restore(conf Configurator) {
 	gts.Async(func() (func(), error) {
 		c, err := conf.Configuration()
 		if err != nil {
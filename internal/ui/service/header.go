@@ -65,6 +65,9 @@ var sessionMenuCSS = primitives.PrepareClassCSS("session-menu", `
 func NewHeader() *Header {
 	menu := glib.MenuNew()
 	menu.Append("Preferences", "app.preferences")
+	menu.Append("Export Profile…", "app.export-profile")
+	menu.Append("Import Profile…", "app.import-profile")
+	menu.Append("Logs", "app.logs")
 	menu.Append("Quit", "app.quit")
 
 	appmenu := NewAppMenu()
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-gtk/internal/crash"
 	"github.com/diamondburned/cchat-gtk/internal/gts"
 	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
 	"github.com/diamondburned/cchat-gtk/internal/ui/primitives/autoscroll"
@@ -129,6 +130,8 @@ func (s *Session) inputActivate(v *gtk.TextView, ev *gdk.Event) bool {
 	s.buffer.Systemlnf("%s > %q", then.Format(time.Kitchen), words)
 
 	go func() {
+		defer crash.Recover()
+
 		out, err := s.cmder.Run(words)
 
 		gts.ExecAsync(func() {
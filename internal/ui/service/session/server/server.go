@@ -4,11 +4,13 @@ import (
 	"context"
 
 	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-gtk/internal/crash"
 	"github.com/diamondburned/cchat-gtk/internal/gts"
 	"github.com/diamondburned/cchat-gtk/internal/log"
 	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
 	"github.com/diamondburned/cchat-gtk/internal/ui/primitives/actions"
 	"github.com/diamondburned/cchat-gtk/internal/ui/rich"
+	"github.com/diamondburned/cchat-gtk/internal/ui/service/chanconf"
 	"github.com/diamondburned/cchat-gtk/internal/ui/service/savepath"
 	"github.com/diamondburned/cchat-gtk/internal/ui/service/session/server/button"
 	"github.com/diamondburned/cchat-gtk/internal/ui/service/session/server/commander"
@@ -196,6 +198,9 @@ func (r *ServerRow) Init() {
 
 	case messenger != nil:
 		primitives.AddClass(r, "server-message")
+		r.ActionsMenu.AddAction("Channel Settings", func() {
+			chanconf.Spawn(r, r.name.String())
+		})
 		r.Button.SetClicked(func(active bool) {
 			if active {
 				r.ctrl.MessengerSelected(r)
@@ -275,6 +280,8 @@ func (r *ServerRow) load(finish func(error)) {
 	}
 
 	go func() {
+		defer crash.Recover()
+
 		stop, err := list.Servers(children)
 		if err != nil {
 			log.Error(errors.Wrap(err, "Failed to get servers"))
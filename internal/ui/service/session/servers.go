@@ -2,6 +2,7 @@ package session
 
 import (
 	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-gtk/internal/crash"
 	"github.com/diamondburned/cchat-gtk/internal/gts"
 	"github.com/diamondburned/cchat-gtk/internal/humanize"
 	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
@@ -145,6 +146,8 @@ func (s *Servers) load() {
 	lister := s.Lister
 
 	go func() {
+		defer crash.Recover()
+
 		stop, err := lister.Servers(s)
 		gts.ExecAsync(func() {
 			if err != nil {
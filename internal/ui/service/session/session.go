@@ -4,9 +4,11 @@ import (
 	"context"
 
 	"github.com/diamondburned/cchat"
+	"github.com/diamondburned/cchat-gtk/internal/crash"
 	"github.com/diamondburned/cchat-gtk/internal/gts"
 	"github.com/diamondburned/cchat-gtk/internal/keyring"
 	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/diamondburned/cchat-gtk/internal/offline"
 	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
 	"github.com/diamondburned/cchat-gtk/internal/ui/primitives/actions"
 	"github.com/diamondburned/cchat-gtk/internal/ui/primitives/drag"
@@ -59,6 +61,9 @@ type Row struct {
 	iconBox *gtk.EventBox
 	icon    *roundimage.StillImage // nillable
 
+	// offlineBadge is shown over the icon while offline.
+	offlineBadge *gtk.Image
+
 	ctrl        Controller
 	parentcrumb traverse.Breadcrumber
 
@@ -123,6 +128,14 @@ var rowCSS = primitives.PrepareClassCSS("session-row",
 
 var rowIconCSS = primitives.PrepareClassCSS("session-icon", ``)
 
+var rowOfflineCSS = primitives.PrepareClassCSS("session-offline", `
+	.session-offline {
+		padding: 2px;
+		border-radius: 99px;
+		background-color: @theme_bg_color;
+	}
+`)
+
 const (
 	IconSize = 42
 	IconName = "face-plain-symbolic"
@@ -163,11 +176,27 @@ func newRow(parent traverse.Breadcrumber, name text.Rich, ctrl Controller) *Row
 	row.iconBox.Add(row.icon)
 	rich.BindRoundImage(row.icon, &row.name, true)
 
+	row.offlineBadge, _ = gtk.ImageNewFromIconName("network-offline-symbolic", gtk.ICON_SIZE_MENU)
+	row.offlineBadge.SetHAlign(gtk.ALIGN_END)
+	row.offlineBadge.SetVAlign(gtk.ALIGN_END)
+	row.offlineBadge.SetTooltipText("Offline")
+	row.offlineBadge.SetVisible(offline.IsOffline())
+	rowOfflineCSS(row.offlineBadge)
+
+	overlay, _ := gtk.OverlayNew()
+	overlay.Add(row.iconBox)
+	overlay.AddOverlay(row.offlineBadge)
+	overlay.Show()
+
 	row.ListBoxRow, _ = gtk.ListBoxRowNew()
-	row.ListBoxRow.Add(row.iconBox)
+	row.ListBoxRow.Add(overlay)
 	row.ListBoxRow.Show()
 	rowCSS(row.ListBoxRow)
 
+	// Toggle the badge when we go offline or back online.
+	removeOfflineHandler := offline.OnChange(row.offlineBadge.SetVisible)
+	row.ListBoxRow.Connect("destroy", removeOfflineHandler)
+
 	row.name.OnUpdate(func() {
 		// TODO: proper popovers instead of tooltips.
 		row.ListBoxRow.SetTooltipText(row.name.String())
@@ -307,6 +336,8 @@ func (r *Row) SetFailed(err error) {
 
 func (r *Row) RestoreSession(res cchat.SessionRestorer, k keyring.Session) {
 	go func() {
+		defer crash.Recover()
+
 		s, err := res.RestoreSession(k.Data)
 		if err != nil {
 			err = errors.Wrapf(err, "failed to restore session %s (%s)", k.ID, k.Name)
@@ -366,6 +397,8 @@ func (r *Row) RemoveSession() {
 
 	// Asynchrously disconnect.
 	go func() {
+		defer crash.Recover()
+
 		if err := session.Disconnect(); err != nil {
 			log.Error(errors.Wrap(err, "non-fatal; failed to disconnect removed session"))
 		}
@@ -5,7 +5,9 @@ import (
 	"github.com/diamondburned/cchat-gtk/icons"
 	"github.com/diamondburned/cchat-gtk/internal/gts"
 	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/diamondburned/cchat-gtk/internal/ui/config/bundle"
 	"github.com/diamondburned/cchat-gtk/internal/ui/config/preferences"
+	"github.com/diamondburned/cchat-gtk/internal/ui/logview"
 	"github.com/diamondburned/cchat-gtk/internal/ui/messages"
 	"github.com/diamondburned/cchat-gtk/internal/ui/primitives"
 	"github.com/diamondburned/cchat-gtk/internal/ui/service"
@@ -94,6 +96,9 @@ func NewApplication() *App {
 	// Bind the preferences action for our GAction button in the header popover.
 	// The action name for this is "app.preferences".
 	gts.AddAppAction("preferences", preferences.SpawnPreferenceDialog)
+	gts.AddAppAction("export-profile", bundle.SpawnExport)
+	gts.AddAppAction("import-profile", bundle.SpawnImport)
+	gts.AddAppAction("logs", logview.Spawn)
 
 	// We should assert folded state based on the window's width instead of the
 	// leaflet's state, since doing that might cause a feedback loop.
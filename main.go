@@ -6,8 +6,12 @@ import (
 
 	"github.com/diamondburned/cchat-gtk/internal/gts"
 	"github.com/diamondburned/cchat-gtk/internal/log"
+	"github.com/diamondburned/cchat-gtk/internal/offline"
 	"github.com/diamondburned/cchat-gtk/internal/ui"
 	"github.com/diamondburned/cchat-gtk/internal/ui/config"
+	"github.com/diamondburned/cchat-gtk/internal/ui/credentials"
+	"github.com/diamondburned/cchat-gtk/internal/ui/logview"
+	"github.com/diamondburned/cchat-gtk/internal/ui/rich/parser/hl"
 	"github.com/diamondburned/cchat/services"
 
 	_ "github.com/diamondburned/cchat-discord"
@@ -26,6 +30,13 @@ func main() {
 	gts.Main(func() gts.MainApplication {
 		var app = ui.NewApplication()
 
+		// Unlock the encrypted keyring before any session is restored.
+		credentials.PromptPassphrase()
+
+		// Restore the configs before the services are added, so that their
+		// requests already use the settings such as the proxy.
+		config.Restore()
+
 		// Load all cchat services.
 		srvcs, errs := services.Get()
 		if len(errs) > 0 {
@@ -39,8 +50,17 @@ func main() {
 			app.AddService(srvc)
 		}
 
-		// Restore the configs.
-		config.Restore()
+		// Reload the configs and user.css on changes.
+		config.Watch()
+
+		// Follow the network state to go offline and back online.
+		offline.Monitor()
+
+		// Highlight code with the style for the light or dark theme.
+		hl.WatchTheme()
+
+		// Offer to show the crash reports from the last session.
+		logview.PromptCrashReports()
 
 		// heapprofiler.Start("/tmp/cchat-gtk")
 		// gts.App.Window.Window.Connect("destroy", heapprofiler.Stop)